@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"go/token"
+	"math"
+	"regexp"
+	"strings"
+)
+
+var declRegexp = regexp.MustCompile(`^(?:func\s*(?:\([^)]*\)\s*)?|(?:type|var|const)\s+)([\pL_][\pL\pN_]*)`)
+
+// isAPI reports whether a changed line of file declares an exported Go
+// identifier.
+func isAPI(file, line string) bool {
+	if !strings.HasSuffix(file, ".go") {
+		return false
+	}
+	match := declRegexp.FindStringSubmatch(line)
+	return match != nil && token.IsExported(match[1])
+}
+
+// commitAPI counts changed lines declaring exported identifiers per file of
+// a commit, for -prefer-api.
+func commitAPI(ctx context.Context, commit *Commit, diff DiffFunc) map[string]int {
+	api := make(map[string]int)
+	p, err := diff(ctx, commit.ID)
+	if err != nil {
+		return api
+	}
+	for file, n := range p.API {
+		api[packageOf(canonicalFile(file))] += n
+	}
+	return api
+}
+
+// commitHunks counts hunks per file of a commit, for -score-by.
+func commitHunks(ctx context.Context, commit *Commit, diff DiffFunc) map[string]int {
+	hunks := make(map[string]int)
+	p, err := diff(ctx, commit.ID)
+	if err != nil {
+		return hunks
+	}
+	for _, h := range p.Hunks {
+		hunks[packageOf(canonicalFile(h.File))]++
+	}
+	return hunks
+}
+
+// api2boost turns changed API lines into a score multiplier: 1 plus log2 of 1
+// plus their count.
+func api2boost(n int) float64 {
+	return 1 + math.Log2(1+float64(n))
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+)
+
+var fileBlame = make(map[string]map[string]int)
+
+// GitBlame counts lines of a file at HEAD by author. It returns nil if the
+// file cannot be blamed.
+func GitBlame(ctx context.Context, file string) map[string]int {
+	if count, ok := fileBlame[file]; ok {
+		return count
+	}
+	var count map[string]int
+	b, err := exec.CommandContext(ctx, "git", "blame", "--line-porcelain", "HEAD", "--", file).Output()
+	if err == nil {
+		count = make(map[string]int)
+		s := bufio.NewScanner(bytes.NewReader(b))
+		s.Buffer(nil, len(b)+1)
+		for s.Scan() {
+			if name := strings.TrimPrefix(s.Text(), "author "); name != s.Text() {
+				count[name]++
+			}
+		}
+	}
+	fileBlame[file] = count
+	return count
+}
+
+// findBlameOwners sets the author of most lines at HEAD of each target.
+func findBlameOwners(ctx context.Context, targets []*Target) {
+	for _, t := range targets {
+		count := make(map[string]int)
+		var total int
+		for _, file := range t.Files {
+			for name, n := range GitBlame(ctx, file) {
+				count[name] += n
+				total += n
+			}
+		}
+		t.BlameOwner, t.BlameShare = "", 0
+		var max int
+		for name, n := range count {
+			if n > max || n == max && name < t.BlameOwner {
+				t.BlameOwner = name
+				max = n
+			}
+		}
+		if total > 0 {
+			t.BlameShare = float64(max) / float64(total)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HourBucket counts commits authored in an hour of the day, and how many of
+// the files they changed were changed again later.
+type HourBucket struct {
+	Hour    int `json:"hour"`
+	Commits int `json:"commits"`
+	Churned int `json:"churned"`
+}
+
+// WeekdayBucket is HourBucket for a day of the week.
+type WeekdayBucket struct {
+	Weekday string `json:"weekday"`
+	Commits int    `json:"commits"`
+	Churned int    `json:"churned"`
+}
+
+// countChurn buckets commits by their author time in -tz, and counts the
+// files they changed that were changed again later.
+func countChurn(commits []*Commit, n int, bucket func(t time.Time) int) (count, churned []int) {
+	exts := fileExts()
+	last := make(map[string]int64)
+	for _, commit := range commits {
+		for _, diff := range commit.Diff {
+			if t := commit.Author.Time.Unix(); t > last[diff.File] {
+				last[diff.File] = t
+			}
+		}
+	}
+	count = make([]int, n)
+	churned = make([]int, n)
+	for _, commit := range commits {
+		i := bucket(commit.Author.Time.In(location))
+		count[i]++
+		for _, diff := range commit.Diff {
+			if !keepFile(diff.File) || !hasExt(diff.File, exts) {
+				continue
+			}
+			if last[diff.File] > commit.Author.Time.Unix() {
+				churned[i]++
+			}
+		}
+	}
+	return
+}
+
+func countByHour(commits []*Commit) (buckets []*HourBucket) {
+	count, churned := countChurn(commits, 24, time.Time.Hour)
+	for i := range count {
+		buckets = append(buckets, &HourBucket{Hour: i, Commits: count[i], Churned: churned[i]})
+	}
+	return
+}
+
+// countByWeekday starts the week on Monday.
+func countByWeekday(commits []*Commit) (buckets []*WeekdayBucket) {
+	count, churned := countChurn(commits, 7, func(t time.Time) int {
+		return (int(t.Weekday()) + 6) % 7
+	})
+	for i := range count {
+		buckets = append(buckets, &WeekdayBucket{
+			Weekday: time.Weekday((i + 1) % 7).String(),
+			Commits: count[i],
+			Churned: churned[i],
+		})
+	}
+	return
+}
+
+func printChurn(title string, labels []string, count, churned []int) {
+	if len(labels) == 0 {
+		return
+	}
+	var max, width int
+	for i, n := range churned {
+		if n > max {
+			max = n
+		}
+		if len(labels[i]) > width {
+			width = len(labels[i])
+		}
+	}
+	fmt.Println(title)
+	for i, label := range labels {
+		var bar string
+		if max > 0 {
+			bar = strings.Repeat("#", churned[i]*40/max)
+		}
+		row := fmt.Sprintf("   %-*s %4d %4d %s", width, label, count[i], churned[i], bar)
+		fmt.Println(strings.TrimRight(row, " "))
+	}
+	fmt.Println()
+}
+
+func printByHour(buckets []*HourBucket) {
+	var labels []string
+	var count, churned []int
+	for _, b := range buckets {
+		labels = append(labels, fmt.Sprintf("%02d:00", b.Hour))
+		count = append(count, b.Commits)
+		churned = append(churned, b.Churned)
+	}
+	printChurn("churned files by hour of commit:", labels, count, churned)
+}
+
+func printByWeekday(buckets []*WeekdayBucket) {
+	var labels []string
+	var count, churned []int
+	for _, b := range buckets {
+		labels = append(labels, b.Weekday)
+		count = append(count, b.Commits)
+		churned = append(churned, b.Churned)
+	}
+	printChurn("churned files by weekday of commit:", labels, count, churned)
+}
@@ -0,0 +1,79 @@
+package main
+
+import "fmt"
+
+// BudgetPick is a target recommended by -budget, with the score of its files
+// that no earlier pick covers.
+type BudgetPick struct {
+	Name       string  `json:"name"`
+	Gain       float64 `json:"gain"`
+	Cumulative float64 `json:"cumulative"`
+}
+
+// Budget is the set of at most -budget targets that covers the most score.
+type Budget struct {
+	Picks   []*BudgetPick `json:"picks"`
+	Covered float64       `json:"covered"`
+	Total   float64       `json:"total"`
+}
+
+// planBudget greedily picks up to k targets, each covering the most score of
+// single-file targets not covered yet, so that a group and its members are
+// not picked twice.
+func planBudget(targets []*Target, k int) *Budget {
+	fileScore := make(map[string]float64)
+	b := new(Budget)
+	for _, t := range targets {
+		if len(t.Files) == 1 {
+			fileScore[t.Files[0]] = t.Score
+			b.Total += t.Score
+		}
+	}
+	covered := make(map[string]bool)
+	picked := make(map[*Target]bool)
+	for len(b.Picks) < k {
+		var best *Target
+		var bestGain float64
+		// targets are ranked, so ties go to the higher score
+		for _, t := range targets {
+			if picked[t] {
+				continue
+			}
+			var gain float64
+			for _, file := range t.Files {
+				if !covered[file] {
+					gain += fileScore[file]
+				}
+			}
+			if gain > bestGain {
+				best, bestGain = t, gain
+			}
+		}
+		if best == nil {
+			break
+		}
+		picked[best] = true
+		for _, file := range best.Files {
+			covered[file] = true
+		}
+		b.Covered += bestGain
+		b.Picks = append(b.Picks, &BudgetPick{
+			Name:       best.Name,
+			Gain:       bestGain,
+			Cumulative: b.Covered,
+		})
+	}
+	return b
+}
+
+func printBudget(b *Budget) {
+	if b == nil || len(b.Picks) == 0 {
+		return
+	}
+	fmt.Println("budget:")
+	for _, p := range b.Picks {
+		fmt.Printf("%8.*f %s %8.*f\n", *precision, p.Gain, pad(shorten(p.Name, 40), 40), *precision, p.Cumulative)
+	}
+	fmt.Printf("covers %.*f of %.*f (%.0f%%)\n", *precision, b.Covered, *precision, b.Total, b.Covered/b.Total*100)
+	fmt.Println()
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+)
+
+// CloneBundle clones a git bundle into a temporary directory, which the
+// caller removes.
+func CloneBundle(ctx context.Context, path string) (string, error) {
+	dir, err := ioutil.TempDir("", "refactor-bundle-")
+	if err != nil {
+		return "", err
+	}
+	out, err := exec.CommandContext(ctx, "git", "clone", "--quiet", "--no-checkout", path, dir).CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("bundle %s: %s", path, bytes.TrimSpace(out))
+	}
+	return dir, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// minGitVersion is the oldest git with log -L, used for drill-down commands.
+var minGitVersion = [2]int{1, 8}
+
+var gitVersionRegexp = regexp.MustCompile(`^git version (\d+)\.(\d+)`)
+
+// Check verifies that git works, that the current directory is a repository
+// and that the inspected window has commits, and writes one line per check.
+func Check(ctx context.Context, w io.Writer) error {
+	checks := []struct {
+		name string
+		fn   func(context.Context) (string, error)
+	}{
+		{"git", checkGit},
+		{"repository", checkRepo},
+		{"commits", checkCommits},
+	}
+	var failed int
+	for _, c := range checks {
+		msg, err := c.fn(ctx)
+		if err != nil {
+			fmt.Fprintf(w, "%s %s: %v\n", paint(colorRed, "FAIL"), c.name, err)
+			failed++
+			// later checks depend on earlier ones
+			break
+		}
+		fmt.Fprintf(w, "ok   %s: %s\n", c.name, msg)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+func checkGit(ctx context.Context) (string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", err
+	}
+	b, err := exec.CommandContext(ctx, "git", "version").Output()
+	if err != nil {
+		return "", err
+	}
+	version := strings.TrimSpace(string(b))
+	match := gitVersionRegexp.FindStringSubmatch(version)
+	if match == nil {
+		return "", fmt.Errorf("unknown version %q", version)
+	}
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	if major < minGitVersion[0] || major == minGitVersion[0] && minor < minGitVersion[1] {
+		return "", fmt.Errorf("%s is older than %d.%d", version, minGitVersion[0], minGitVersion[1])
+	}
+	return version, nil
+}
+
+func checkRepo(ctx context.Context) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--show-toplevel")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	b, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s", msg)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func checkCommits(ctx context.Context) (string, error) {
+	args := []string{"rev-list", "--count"}
+	window := fmt.Sprintf("after %s and before %s", *after, *before)
+	if *mergeBase != "" {
+		base, err := GitMergeBase(ctx, *mergeBase)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, base+"..HEAD")
+		window = fmt.Sprintf("on HEAD since %s", *mergeBase)
+	} else if *fromTag != "" {
+		r, err := GitTagRange(ctx, *fromTag, *toTag)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, r)
+		window = "in " + r
+	} else {
+		args = append(args, "--all",
+			fmt.Sprintf("--after=%s", *after),
+			fmt.Sprintf("--before=%s", *before))
+	}
+	b, err := exec.CommandContext(ctx, "git", args...).Output()
+	if err != nil {
+		return "", err
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(string(b)))
+	if n == 0 {
+		return "", fmt.Errorf("no commits %s", window)
+	}
+	return fmt.Sprintf("%d %s", n, window), nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	colorRed   = "31"
+	colorDim   = "2"
+	colorMuted = "90"
+)
+
+var colorEnabled bool
+
+// setupColor decides whether output is colorized. In auto mode, color is
+// only used when stdout is a terminal and NO_COLOR is not set.
+func setupColor(mode string) error {
+	switch mode {
+	case "always":
+		colorEnabled = true
+	case "never":
+		colorEnabled = false
+	case "auto":
+		colorEnabled = os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+	default:
+		return fmt.Errorf("invalid -color %q: must be auto, always or never", mode)
+	}
+	return nil
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// paint wraps s with the ANSI code if color is enabled.
+func paint(code, s string) string {
+	if !colorEnabled {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
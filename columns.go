@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type column struct {
+	name   string
+	format func(t *Target) string
+}
+
+var columns = []column{
+	{"score", func(t *Target) string {
+		return paint(colorRed, fmt.Sprintf("%*.*f", scoreWidth, *precision, t.Score))
+	}},
+	{"name", func(t *Target) string {
+		return pad(shorten(t.Name, 40), 40)
+	}},
+	{"commits", func(t *Target) string {
+		return paint(colorDim, fmt.Sprintf("%4d", len(t.Commit)))
+	}},
+	{"authors", func(t *Target) string {
+		authors := make(map[string]bool)
+		for _, commit := range t.Commit {
+			authors[commit.Author.Name] = true
+		}
+		return fmt.Sprintf("%4d", len(authors))
+	}},
+	{"owner", func(t *Target) string {
+		if len(t.Owners) == 0 {
+			return ""
+		}
+		return t.Owners[0].Name
+	}},
+	{"blame", func(t *Target) string {
+		return t.BlameOwner
+	}},
+	{"add", func(t *Target) string {
+		return fmt.Sprintf("%6d", t.Add)
+	}},
+	{"delete", func(t *Target) string {
+		return fmt.Sprintf("%6d", t.Delete)
+	}},
+	{"skew", func(t *Target) string {
+		return fmt.Sprintf("%+6.2f", t.Skew)
+	}},
+	{"instability", func(t *Target) string {
+		return fmt.Sprintf("%5.2f", t.Instability)
+	}},
+	{"velocity", func(t *Target) string {
+		return fmt.Sprintf("%8.*f", *precision, t.Velocity)
+	}},
+	{"entropy", func(t *Target) string {
+		return fmt.Sprintf("%5.2f", t.Entropy)
+	}},
+	{"contested", func(t *Target) string {
+		return fmt.Sprintf("%4d", t.Contested)
+	}},
+	{"first", func(t *Target) string {
+		first, _ := t.timeRange()
+		return first.In(location).Format("2006-01-02")
+	}},
+	{"last", func(t *Target) string {
+		_, last := t.timeRange()
+		return last.In(location).Format("2006-01-02")
+	}},
+}
+
+var layout []column
+
+// parseColumns sets the text layout from a comma-separated list of column
+// names.
+func parseColumns(s string) error {
+	byName := make(map[string]column)
+	var names []string
+	for _, c := range columns {
+		byName[c.name] = c
+		names = append(names, c.name)
+	}
+	sort.Strings(names)
+	layout = nil
+	for _, name := range strings.Split(s, ",") {
+		c, ok := byName[strings.TrimSpace(name)]
+		if !ok {
+			return fmt.Errorf("unknown column %q: must be one of %s", name, strings.Join(names, ", "))
+		}
+		layout = append(layout, c)
+	}
+	return nil
+}
+
+// scoreWidth is the width of the score column, at least 8 so that reasons
+// stay aligned below it.
+var scoreWidth = 8
+
+// sizeScoreColumn fits the score column to the scores of targets.
+func sizeScoreColumn(targets []*Target) {
+	scoreWidth = 8
+	for _, t := range targets {
+		if w := len(fmt.Sprintf("%.*f", *precision, t.Score)); w > scoreWidth {
+			scoreWidth = w
+		}
+	}
+}
+
+func formatRow(t *Target) string {
+	var cells []string
+	for _, c := range layout {
+		cells = append(cells, c.format(t))
+	}
+	return strings.Join(cells, " ")
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os/exec"
+	"strings"
+)
+
+var fileComplexity = make(map[string]int)
+
+// GitComplexity returns the cyclomatic complexity of a Go file at HEAD, which
+// is the sum of the complexity of its functions. It returns 0 if the file is
+// not Go or cannot be parsed.
+func GitComplexity(ctx context.Context, file string) int {
+	if c, ok := fileComplexity[file]; ok {
+		return c
+	}
+	var c int
+	if strings.HasSuffix(file, ".go") {
+		b, err := exec.CommandContext(ctx, "git", "show", "HEAD:"+file).Output()
+		if err == nil {
+			c = complexity(b)
+		}
+	}
+	fileComplexity[file] = c
+	return c
+}
+
+func complexity(src []byte) (c int) {
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		return
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl, *ast.FuncLit:
+			c++
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			c++
+		case *ast.CaseClause:
+			if n.List != nil {
+				c++
+			}
+		case *ast.CommClause:
+			if n.Comm != nil {
+				c++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				c++
+			}
+		}
+		return true
+	})
+	return
+}
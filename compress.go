@@ -0,0 +1,47 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+)
+
+// closeStdout flushes stdout before exiting. It is replaced by gzipStdout.
+var closeStdout = func() error { return nil }
+
+// gzipStdout replaces os.Stdout with a pipe whose output is written gzip
+// compressed to the original stdout.
+func gzipStdout() error {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	out := os.Stdout
+	os.Stdout = w
+	zw := gzip.NewWriter(out)
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(zw, r)
+		if cerr := zw.Close(); err == nil {
+			err = cerr
+		}
+		done <- err
+	}()
+	closeStdout = func() error {
+		w.Close()
+		os.Stdout = out
+		return <-done
+	}
+	return nil
+}
+
+// exit flushes stdout and exits with code.
+func exit(code int) {
+	if err := closeStdout(); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		if code == 0 {
+			code = 1
+		}
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// maxCouplingFiles skips commits touching more files, like mass renames,
+// which would add many pairs and say little about coupling.
+const maxCouplingFiles = 100
+
+// Coupling is how often two files change together. AB is the probability that
+// a commit changing A also changes B, and BA the other way around.
+type Coupling struct {
+	A     string  `json:"a"`
+	B     string  `json:"b"`
+	Count int     `json:"count"`
+	AB    float64 `json:"ab"`
+	BA    float64 `json:"ba"`
+}
+
+type ByCoupling []*Coupling
+
+func (s ByCoupling) Len() int      { return len(s) }
+func (s ByCoupling) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s ByCoupling) Less(i, j int) bool {
+	ci, cj := math.Max(s[i].AB, s[i].BA), math.Max(s[j].AB, s[j].BA)
+	if ci != cj {
+		return ci > cj
+	}
+	if s[i].Count != s[j].Count {
+		return s[i].Count > s[j].Count
+	}
+	if s[i].A != s[j].A {
+		return s[i].A < s[j].A
+	}
+	return s[i].B < s[j].B
+}
+
+// findCoupling returns the top k pairs of files that changed together in at
+// least min commits.
+func findCoupling(commits []*Commit, min, k int) (pairs []*Coupling) {
+	exts := fileExts()
+	count := make(map[string]int)
+	co := make(map[[2]string]int)
+	for _, commit := range commits {
+		var files []string
+		for _, diff := range commit.Diff {
+			if keepFile(diff.File) && hasExt(diff.File, exts) {
+				files = append(files, diff.File)
+			}
+		}
+		if len(files) > maxCouplingFiles {
+			continue
+		}
+		sort.Strings(files)
+		for i, a := range files {
+			count[a]++
+			for _, b := range files[i+1:] {
+				co[[2]string{a, b}]++
+			}
+		}
+	}
+	for pair, n := range co {
+		if n < min {
+			continue
+		}
+		pairs = append(pairs, &Coupling{
+			A:     pair[0],
+			B:     pair[1],
+			Count: n,
+			AB:    float64(n) / float64(count[pair[0]]),
+			BA:    float64(n) / float64(count[pair[1]]),
+		})
+	}
+	sort.Sort(ByCoupling(pairs))
+	if len(pairs) > k {
+		pairs = pairs[:k]
+	}
+	return
+}
+
+func printCoupling(pairs []*Coupling) {
+	if len(pairs) == 0 {
+		return
+	}
+	fmt.Println("coupled files:")
+	for _, c := range pairs {
+		fmt.Printf("    %4.2f %4.2f %4d %s <-> %s\n", c.AB, c.BA, c.Count, c.A, c.B)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// coverage maps file paths in a Go coverage profile, which are import paths,
+// to the fraction of statements covered.
+var coverage map[string]float64
+
+// parseCoverage reads a profile written by go test -coverprofile.
+func parseCoverage(path string) error {
+	coverage = nil
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	total := make(map[string]int)
+	covered := make(map[string]int)
+	s := bufio.NewScanner(f)
+	var n int
+	for s.Scan() {
+		n++
+		line := s.Text()
+		if n == 1 && strings.HasPrefix(line, "mode: ") || line == "" {
+			continue
+		}
+		// file.go:line.col,line.col statements count
+		fields := strings.Fields(line)
+		i := strings.LastIndexByte(line, ':')
+		if len(fields) != 3 || i < 0 {
+			return fmt.Errorf("%s:%d: bad coverage block %q", path, n, line)
+		}
+		stmts, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return fmt.Errorf("%s:%d: bad coverage block %q", path, n, line)
+		}
+		count, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return fmt.Errorf("%s:%d: bad coverage block %q", path, n, line)
+		}
+		file := line[:i]
+		total[file] += stmts
+		if count > 0 {
+			covered[file] += stmts
+		}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	coverage = make(map[string]float64)
+	for file, stmts := range total {
+		if stmts > 0 {
+			coverage[file] = float64(covered[file]) / float64(stmts)
+		}
+	}
+	return nil
+}
+
+// coverage2boost favors poorly covered files: it is 1.5 for a file without
+// coverage, 0.5 for a fully covered one and 1 for a file not in the profile.
+// If several paths in the profile end with file, the shortest one is used.
+func coverage2boost(file string) float64 {
+	var best string
+	for path := range coverage {
+		if path != file && !strings.HasSuffix(path, "/"+file) {
+			continue
+		}
+		if best == "" || len(path) < len(best) || len(path) == len(best) && path < best {
+			best = path
+		}
+	}
+	if best == "" {
+		return 1
+	}
+	return 1.5 - coverage[best]
+}
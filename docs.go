@@ -0,0 +1,46 @@
+package main
+
+import "context"
+
+var docPatterns []string
+
+// dropDocCommits removes commits that only change -doc-files or comments.
+func dropDocCommits(ctx context.Context, commits []*Commit, diff DiffFunc) (kept []*Commit) {
+	for _, commit := range commits {
+		if !isDocCommit(ctx, commit, diff) {
+			kept = append(kept, commit)
+		}
+	}
+	return
+}
+
+func isDocCommit(ctx context.Context, commit *Commit, diff DiffFunc) bool {
+	var code []string
+	for _, d := range commit.Diff {
+		if matchFile(docPatterns, d.File) {
+			continue
+		}
+		if d.Add+d.Delete == 0 {
+			// renamed without changes, which is not documentation
+			return false
+		}
+		code = append(code, d.File)
+	}
+	if len(code) == 0 {
+		return len(commit.Diff) > 0
+	}
+	p, err := diff(ctx, commit.ID)
+	if err != nil {
+		return false
+	}
+	changed := make(map[string]int)
+	for file, n := range p.Code {
+		changed[canonicalFile(file)] += n
+	}
+	for _, file := range code {
+		if changed[file] > 0 {
+			return false
+		}
+	}
+	return true
+}
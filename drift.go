@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+var configPatterns []string
+
+// settingRegexp matches changed lines that set a key or pin a dependency,
+// like `"lodash": "^4.17.21",`, `timeout = 30` or `golang.org/x/net v0.1.0`.
+var settingRegexp = regexp.MustCompile(`^(?:"?[\w.@/\-]+"?\s*[:=]|(?:require |replace |exclude )?[\w.@/\-]+\s+v?[0-9]|[\w.@/\-]+@[\w.^~\-]+|[{}\[\],]+$)`)
+
+// driftShare is how many changed lines of a config file must be settings for
+// the change to be drift.
+const driftShare = 0.8
+
+// ConfigDrift is churn of -config-files that changed mostly settings, which
+// is left out of the ranking.
+type ConfigDrift struct {
+	Files int `json:"files"`
+	Lines int `json:"lines"`
+	// Share is of all changed lines of inspected files.
+	Share float64 `json:"share"`
+}
+
+// keepAll is a LineFilter that keeps every line.
+func keepAll(string) bool { return true }
+
+var settingDiff = NewGitDiff(keepAll)
+
+// isConfigDrift reports whether diff of commit changed a -config-files file
+// mostly in settings. If the commit cannot be diffed, the name decides.
+func isConfigDrift(ctx context.Context, commit *Commit, diff Diff) bool {
+	if !matchFile(configPatterns, diff.File) {
+		return false
+	}
+	p, err := settingDiff(ctx, commit.ID)
+	if err != nil {
+		return true
+	}
+	var lines, settings int
+	for _, l := range append(p.Add, p.Del...) {
+		if canonicalFile(l.File) != diff.File || l.Line == "" {
+			continue
+		}
+		lines++
+		if settingRegexp.MatchString(l.Line) {
+			settings++
+		}
+	}
+	return float64(settings) >= driftShare*float64(lines)
+}
+
+func printConfigDrift(d *ConfigDrift) {
+	if d == nil || d.Lines == 0 {
+		return
+	}
+	fmt.Printf("config drift: %d lines in %d files (%.0f%% of changed lines), not ranked\n\n",
+		d.Lines, d.Files, d.Share*100)
+}
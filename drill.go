@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// shellQuote quotes s for sh.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// drillDown suggests git commands to investigate the history of t over the
+// inspected commits. There are none for commits that are not read from git.
+func drillDown(t *Target) (cmds []string) {
+	if *patches != "" || *commitsJSON != "" || *logFile != "" {
+		return nil
+	}
+	var window, lineWindow string
+	if *mergeBase != "" {
+		window = shellQuote(*mergeBase) + "..HEAD"
+		lineWindow = window
+	} else if *fromTag != "" {
+		if *toTag == "" {
+			window = shellQuote(*fromTag) + "..HEAD"
+		} else {
+			window = shellQuote(*fromTag) + ".." + shellQuote(*toTag)
+		}
+		lineWindow = window
+	} else {
+		lineWindow = fmt.Sprintf("--after=%s --before=%s", shellQuote(*after), shellQuote(*before))
+		// git log -L cannot follow several refs, so it looks at HEAD only
+		window = "--all " + lineWindow
+	}
+	var files []string
+	for _, file := range t.Files {
+		files = append(files, shellQuote(file))
+	}
+	cmds = append(cmds, fmt.Sprintf("git log -p %s -- %s", window, strings.Join(files, " ")))
+	if t.HotFile != "" {
+		cmds = append(cmds, fmt.Sprintf("git log %s -L %s", lineWindow,
+			shellQuote(fmt.Sprintf("%d,%d:%s", t.HotStart, t.HotEnd, t.HotFile))))
+	}
+	return
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// factor is a multiplier of the edit score of a file, for -explain.
+type factor struct {
+	name  string
+	value float64
+}
+
+// addFactor records f, unless -explain is off or f changes nothing.
+func addFactor(factors []factor, name string, f float64) []factor {
+	if *explainTarget == "" || f == 1 {
+		return factors
+	}
+	return append(factors, factor{name, f})
+}
+
+// fileStep is how a commit scores one file, for -explain.
+type fileStep struct {
+	file    string
+	unit    string
+	edit    float64
+	factors []factor
+	score   float64
+}
+
+// editUnit describes what -score-by counts in diff.
+func editUnit(diff Diff, hunks int) string {
+	switch *scoreBy {
+	case "hunks":
+		return fmt.Sprintf("%d hunks", hunks)
+	case "both":
+		n := diff.Add + diff.Delete
+		return fmt.Sprintf("%d lines (%g) x %d hunks", n, edit2score(n), hunks)
+	}
+	return fmt.Sprintf("%d lines", diff.Add+diff.Delete)
+}
+
+func (s *fileStep) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %g", s.unit, s.edit)
+	for _, f := range s.factors {
+		fmt.Fprintf(&b, " x %s %.2f", f.name, f.value)
+	}
+	fmt.Fprintf(&b, " = %.*f", *precision, s.score)
+	return b.String()
+}
+
+// explain prints how the score of t is computed.
+func explain(t *Target) {
+	fmt.Printf("%s\n\n", t.Name)
+	fmt.Println("edit score:")
+	for i, commit := range t.Commit {
+		steps := t.steps[i]
+		if len(t.Files) == 1 {
+			fmt.Printf("    %s %s\n", shortID(commit.ID), steps[0])
+			continue
+		}
+		if *groupAgg == "max" {
+			fmt.Printf("    %s max of %d files = %.*f\n",
+				shortID(commit.ID), len(steps), *precision, t.contrib[i])
+		} else {
+			fmt.Printf("    %s sum of %d files x %d files = %.*f\n",
+				shortID(commit.ID), len(steps), len(steps), *precision, t.contrib[i])
+		}
+		steps = append([]*fileStep(nil), steps...)
+		sort.Slice(steps, func(i, j int) bool { return steps[i].file < steps[j].file })
+		for _, step := range steps {
+			fmt.Printf("        %s %s\n", step.file, step)
+		}
+	}
+	fmt.Printf("    total = %.*f\n\n", *precision, t.editScore)
+	score := t.Score
+	if t.RawScore != 0 {
+		score = t.RawScore
+		defer fmt.Printf("normalized: %.*f\n", *precision, t.Score)
+	}
+	if *noReasons {
+		fmt.Printf("score: %.*f (reasons skipped)\n", *precision, score)
+		return
+	}
+	fmt.Printf("reason delta: %d\n", t.delta)
+	for _, reason := range t.Reason {
+		fmt.Printf("    %4d %s\n", reason.Count, reason.Line)
+	}
+	fmt.Println()
+	if t.external {
+		fmt.Printf("score: %.*f (from -scorer, built-in %.*f x %d = %.*f)\n",
+			*precision, score, *precision, t.editScore, t.delta, *precision, t.editScore*float64(t.delta))
+	} else {
+		fmt.Printf("score: %.*f x %d = %.*f\n", *precision, t.editScore, t.delta, *precision, score)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parsePatterns splits a comma-separated pattern list. An argument of the
+// form @file reads patterns from file, one per line; blank lines and lines
+// starting with # are ignored.
+func parsePatterns(s string) (patterns []string, err error) {
+	for _, arg := range strings.Split(s, ",") {
+		arg = strings.TrimSpace(arg)
+		if arg == "" {
+			continue
+		}
+		if !strings.HasPrefix(arg, "@") {
+			patterns = append(patterns, arg)
+			continue
+		}
+		f, err := os.Open(arg[1:])
+		if err != nil {
+			return nil, err
+		}
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			line := strings.TrimSpace(s.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			patterns = append(patterns, line)
+		}
+		f.Close()
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// matchFile reports whether file matches any of the glob patterns. A
+// pattern matches the whole path, the base name, or a leading directory.
+func matchFile(patterns []string, file string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, file); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, path.Base(file)); ok {
+			return true
+		}
+		if strings.HasPrefix(file, strings.TrimSuffix(p, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+var includePatterns, excludePatterns []string
+
+// keepFile reports whether file passes -include and -exclude.
+func keepFile(file string) bool {
+	if len(includePatterns) > 0 && !matchFile(includePatterns, file) {
+		return false
+	}
+	return !matchFile(excludePatterns, file)
+}
+
+// extWeights are score multipliers by file extension from -ext-weights. When
+// set, they replace -ext.
+var extWeights map[string]float64
+
+func parseExtWeights(s string) error {
+	extWeights = nil
+	for _, rule := range strings.Split(s, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		i := strings.LastIndexByte(rule, ':')
+		if i <= 0 {
+			return fmt.Errorf("invalid -ext-weights rule %q: must be ext:weight", rule)
+		}
+		w, err := strconv.ParseFloat(rule[i+1:], 64)
+		if err != nil {
+			return fmt.Errorf("invalid -ext-weights rule %q: %v", rule, err)
+		}
+		if extWeights == nil {
+			extWeights = make(map[string]float64)
+		}
+		extWeights[rule[:i]] = w
+	}
+	return nil
+}
+
+// fileExts returns the extensions of files to inspect.
+func fileExts() []string {
+	if extWeights == nil {
+		return strings.Split(*ext, ",")
+	}
+	var exts []string
+	for ext := range extWeights {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// extWeight is the -ext-weights multiplier of the longest extension that
+// file ends with, or 1.
+func extWeight(file string) float64 {
+	w := 1.0
+	var longest int
+	for ext, weight := range extWeights {
+		if len(ext) > longest && strings.HasSuffix(file, ext) {
+			w = weight
+			longest = len(ext)
+		}
+	}
+	return w
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// Fixup is a file that was changed again shortly after a merge brought it
+// in, which hints at an incomplete or risky change.
+type Fixup struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type ByFixupCount []*Fixup
+
+func (s ByFixupCount) Len() int      { return len(s) }
+func (s ByFixupCount) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s ByFixupCount) Less(i, j int) bool {
+	if s[i].Count != s[j].Count {
+		return s[i].Count > s[j].Count
+	}
+	return s[i].Name < s[j].Name
+}
+
+// GitMergeFiles lists files that a merge changed relative to its first parent.
+func GitMergeFiles(ctx context.Context, commit *Commit) (map[string]bool, error) {
+	b, err := exec.CommandContext(ctx, "git", "-c", "core.quotepath=false", "diff", "--name-only", commit.Parents[0], commit.ID).Output()
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]bool)
+	for _, file := range strings.Split(string(bytes.TrimSpace(b)), "\n") {
+		if file != "" {
+			files[canonicalFile(file)] = true
+		}
+	}
+	return files, nil
+}
+
+// findFixups counts, for each file a merge brought in, the later commits that
+// changed it within -fixup-commits commits or -fixup-within of the merge. Like
+// the rest of the analysis, it goes by author time.
+func findFixups(ctx context.Context, commits []*Commit, k int) ([]*Fixup, error) {
+	// git log lists the newest first
+	sorted := make([]*Commit, len(commits))
+	for i, commit := range commits {
+		sorted[len(commits)-1-i] = commit
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Author.Time.Before(sorted[j].Author.Time)
+	})
+	byID := make(map[string]*Commit)
+	for _, commit := range commits {
+		byID[commit.ID] = commit
+	}
+	exts := fileExts()
+	count := make(map[string]int)
+	for i, merge := range sorted {
+		if len(merge.Parents) < 2 {
+			continue
+		}
+		files, err := GitMergeFiles(ctx, merge)
+		if err != nil {
+			return nil, err
+		}
+		merged := ancestors(merge, byID)
+		var n int
+		for _, commit := range sorted[i+1:] {
+			if merged[commit.ID] {
+				continue
+			}
+			n++
+			if !(*fixupCommits > 0 && n <= *fixupCommits) &&
+				!(*fixupWithin > 0 && commit.Author.Time.Sub(merge.Author.Time) <= *fixupWithin) {
+				break
+			}
+			if len(commit.Parents) > 1 {
+				continue
+			}
+			for _, diff := range commit.Diff {
+				if files[diff.File] && keepFile(diff.File) && hasExt(diff.File, exts) {
+					count[diff.File]++
+				}
+			}
+		}
+	}
+	var fixups []*Fixup
+	for name, n := range count {
+		fixups = append(fixups, &Fixup{Name: name, Count: n})
+	}
+	sort.Sort(ByFixupCount(fixups))
+	if len(fixups) > k {
+		fixups = fixups[:k]
+	}
+	return fixups, nil
+}
+
+// ancestors returns the IDs of commits in byID that commit descends from.
+func ancestors(commit *Commit, byID map[string]*Commit) map[string]bool {
+	seen := make(map[string]bool)
+	queue := commit.Parents
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		parent, ok := byID[id]
+		if !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		queue = append(queue, parent.Parents...)
+	}
+	return seen
+}
+
+func printFixups(fixups []*Fixup) {
+	if len(fixups) == 0 {
+		return
+	}
+	fmt.Println("post-merge fixups:")
+	for _, f := range fixups {
+		fmt.Printf("%8d %s\n", f.Count, f.Name)
+	}
+	fmt.Println()
+}
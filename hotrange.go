@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// findHotRange finds the window of -hot-range lines that is changed the most
+// often, the fraction of all changed lines of t that fall into it, and the
+// entropy of changes over lines.
+func (t *Target) findHotRange(hunks []Hunk) {
+	files := make(map[string]bool)
+	for _, file := range t.Files {
+		files[file] = true
+	}
+	changed := make(map[string][]int)
+	var total int
+	for _, h := range hunks {
+		if !files[packageOf(h.File)] {
+			continue
+		}
+		n := h.Lines
+		if n < 1 {
+			// pure deletion
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			changed[h.File] = append(changed[h.File], h.Start+i)
+		}
+		total += n
+	}
+	if total == 0 {
+		return
+	}
+	var names []string
+	for file := range changed {
+		names = append(names, file)
+	}
+	sort.Strings(names)
+
+	var best int
+	for _, file := range names {
+		lines := changed[file]
+		sort.Ints(lines)
+		var i int
+		for j := range lines {
+			for i < j && lines[j]-lines[i] >= *hotRange {
+				i++
+			}
+			if j-i+1 > best {
+				best = j - i + 1
+				t.HotFile = file
+				t.HotStart = lines[i]
+				t.HotEnd = lines[j]
+			}
+		}
+	}
+	t.Concentration = float64(best) / float64(total)
+	t.Entropy = lineEntropy(names, changed, total)
+}
+
+// lineEntropy is the Shannon entropy in bits of how total changes distribute
+// over the sorted lines of each file in names. Changes piling up on a few
+// lines have low entropy; changes spread over the whole file have high
+// entropy.
+func lineEntropy(names []string, changed map[string][]int, total int) float64 {
+	var h float64
+	for _, file := range names {
+		lines := changed[file]
+		for i := 0; i < len(lines); {
+			j := i
+			for j < len(lines) && lines[j] == lines[i] {
+				j++
+			}
+			p := float64(j-i) / float64(total)
+			h -= p * math.Log2(p)
+			i = j
+		}
+	}
+	return h
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// importMinFiles is how many files a commit must change, and how many times
+// more than the median commit, to be an import rather than a change.
+const importMinFiles = 100
+
+// dropImportCommits removes initial import and vendoring commits: the root
+// commit, if roots are known, and commits changing an outlier number of
+// files. It reports what it dropped on stderr.
+func dropImportCommits(commits []*Commit, roots bool) (kept []*Commit) {
+	var sizes []int
+	for _, commit := range commits {
+		sizes = append(sizes, len(commit.Diff))
+	}
+	sort.Ints(sizes)
+	var median int
+	if len(sizes) > 0 {
+		median = sizes[len(sizes)/2]
+	}
+	for _, commit := range commits {
+		n := len(commit.Diff)
+		root := roots && len(commit.Parents) == 0 && commit.ID != stagedID
+		if root || n >= importMinFiles && n > 10*median {
+			fmt.Fprintf(os.Stderr, "excluding import commit %s (%d files); use -include-import to keep it\n",
+				shortID(commit.ID), n)
+			continue
+		}
+		kept = append(kept, commit)
+	}
+	return
+}
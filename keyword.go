@@ -0,0 +1,56 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// keywordRegexp matches -keywords at the start of a word, so that "fix" also
+// matches "fixes" but not "prefix". It is nil unless -keyword-weight is set.
+var keywordRegexp *regexp.Regexp
+
+func parseKeywords(s string) error {
+	keywordRegexp = nil
+	if *keywordWeight == 1 {
+		return nil
+	}
+	var words []string
+	for _, word := range strings.Split(s, ",") {
+		if word = strings.TrimSpace(word); word != "" {
+			words = append(words, regexp.QuoteMeta(strings.ToLower(word)))
+		}
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	var err error
+	keywordRegexp, err = regexp.Compile(`(?i)\b(` + strings.Join(words, "|") + `)`)
+	return err
+}
+
+// commitKeywords returns the keywords found in the message of commit, in
+// lower case.
+func commitKeywords(commit *Commit) (found []string) {
+	for _, line := range commit.Message {
+		for _, match := range keywordRegexp.FindAllStringSubmatch(line, -1) {
+			found = append(found, strings.ToLower(match[1]))
+		}
+	}
+	return
+}
+
+// findKeywords sets the distinct keywords found in the target's commits.
+func (t *Target) findKeywords() {
+	seen := make(map[string]bool)
+	t.Keywords = nil
+	for _, commit := range t.Commit {
+		for _, word := range commitKeywords(commit) {
+			if !seen[word] {
+				seen[word] = true
+				t.Keywords = append(t.Keywords, word)
+			}
+		}
+	}
+	sort.Strings(t.Keywords)
+}
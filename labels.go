@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// commitLabels maps commit IDs, or prefixes of them, to labels from -labels.
+var commitLabels map[string][]string
+
+// labelWeights are score multipliers by label from -label-weights.
+var labelWeights map[string]float64
+
+// parseLabels reads lines of a commit ID followed by comma-separated labels,
+// like "3f4c2a1 bug,tech-debt". Blank lines and lines starting with # are
+// ignored.
+func parseLabels(path string) error {
+	commitLabels = nil
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	commitLabels = make(map[string][]string)
+	s := bufio.NewScanner(f)
+	var n int
+	for s.Scan() {
+		n++
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return fmt.Errorf("%s:%d: want a commit and comma-separated labels: %q", path, n, line)
+		}
+		id := strings.ToLower(fields[0])
+		commitLabels[id] = append(commitLabels[id], strings.Split(fields[1], ",")...)
+	}
+	return s.Err()
+}
+
+func parseLabelWeights(s string) error {
+	labelWeights = make(map[string]float64)
+	for _, rule := range strings.Split(s, ",") {
+		if strings.TrimSpace(rule) == "" {
+			continue
+		}
+		i := strings.IndexByte(rule, '=')
+		if i <= 0 {
+			return fmt.Errorf("invalid -label-weights rule %q: must be label=weight", rule)
+		}
+		w, err := strconv.ParseFloat(rule[i+1:], 64)
+		if err != nil {
+			return fmt.Errorf("invalid -label-weights rule %q: %v", rule, err)
+		}
+		labelWeights[strings.TrimSpace(rule[:i])] = w
+	}
+	return nil
+}
+
+// labelBoost is the largest weight of the labels of commit, whose ID may be
+// abbreviated in -labels, or 1 if it has none with a weight.
+func labelBoost(commit *Commit) float64 {
+	boost := 1.0
+	var found bool
+	for id, labels := range commitLabels {
+		if !strings.HasPrefix(commit.ID, id) {
+			continue
+		}
+		for _, label := range labels {
+			if w, ok := labelWeights[label]; ok && (!found || w > boost) {
+				boost = w
+				found = true
+			}
+		}
+	}
+	return boost
+}
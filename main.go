@@ -3,154 +3,591 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"crypto/sha256"
 	"flag"
 	"fmt"
+	"math"
+	"os"
 	"os/exec"
+	"os/signal"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
+	"unicode/utf8"
 )
 
 type Author struct {
-	Name  string
-	Email string
-	Time  time.Time
+	Name  string    `json:"name"`
+	Email string    `json:"email"`
+	Time  time.Time `json:"time"`
 }
 
 type Diff struct {
-	File   string
-	Add    int
-	Delete int
+	File   string `json:"file"`
+	Add    int    `json:"add"`
+	Delete int    `json:"delete"`
+
+	// From is the path before a rename.
+	From string `json:"from,omitempty"`
 }
 
 type Commit struct {
-	ID      string
-	Tree    string
-	Parent  string
-	Author  Author
-	Message []string
-	Diff    []Diff
+	ID      string   `json:"id"`
+	Tree    string   `json:"tree"`
+	Parent  string   `json:"parent"`
+	Parents []string `json:"parents,omitempty"`
+	Author  Author   `json:"author"`
+	Message []string `json:"message"`
+	Diff    []Diff   `json:"diff"`
+
+	// Squashed are the older commits coalesced into this one by
+	// -squash-window.
+	Squashed []*Commit `json:"squashed,omitempty"`
+
+	// Type, Scope and Subject are parsed from conventional commit subjects.
+	Type    string `json:"type,omitempty"`
+	Scope   string `json:"scope,omitempty"`
+	Subject string `json:"subject,omitempty"`
+}
+
+// logFormat separates commits with RS and fields with US, so that commit
+// messages cannot be mistaken for headers. Authors are mapped by .mailmap
+// unless -mailmap=false.
+func logFormat() string {
+	author := "%aN%x1f%aE"
+	if !*mailmap {
+		author = "%an%x1f%ae"
+	}
+	return "--format=%x1e%H%x1f%T%x1f%P%x1f" + author + "%x1f%at%x1f%B%x1f"
 }
 
 var (
-	commitRegexp  = regexp.MustCompile(`^commit (.+)$`)
-	treeRegexp    = regexp.MustCompile(`^tree (.+)$`)
-	parentRegexp  = regexp.MustCompile(`^parent (.+)$`)
-	authorRegexp  = regexp.MustCompile(`^author ([^ ]+) <([^ ]+)> ([^ ]+) [^ ]+$`)
-	messageRegexp = regexp.MustCompile(`^[ ]{4}(.+)$`)
-	diffRegexp    = regexp.MustCompile(`^([0-9]+)\t([0-9]+)\t(.+)$`)
+	diffRegexp   = regexp.MustCompile(`^([0-9]+)\t([0-9]+)\t(.+)$`)
+	binaryRegexp = regexp.MustCompile(`^-\t-\t(.+)$`)
 )
 
-func GitLog() (commits []*Commit, err error) {
-	b, err := exec.Command("git", "log", "--all",
-		fmt.Sprintf(`--after="%s"`, *after),
-		fmt.Sprintf(`--before="%s"`, *before),
-		"--format=raw", "--numstat").Output()
+func GitLog(ctx context.Context) (commits []*Commit, err error) {
+	args := []string{"-c", "core.quotepath=false", "log"}
+	if *mergeBase != "" {
+		base, err := GitMergeBase(ctx, *mergeBase)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, base+"..HEAD")
+	} else if *fromTag != "" {
+		r, err := GitTagRange(ctx, *fromTag, *toTag)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, r)
+	} else {
+		args = append(args, "--all",
+			fmt.Sprintf(`--after="%s"`, *after),
+			fmt.Sprintf(`--before="%s"`, *before))
+	}
+	b, err := exec.CommandContext(ctx, "git", append(args, logFormat(), "--numstat")...).Output()
 	if err != nil {
 		return
 	}
+	commits, errs := ParseLog(b)
+	if *strict && len(errs) > 0 {
+		for _, e := range errs {
+			fmt.Fprintln(os.Stderr, e)
+		}
+		err = fmt.Errorf("git log: %d lines cannot be parsed", len(errs))
+		return
+	}
+	if *excludeAuthor != "" {
+		commits = excludeAuthors(commits, strings.Split(*excludeAuthor, ","))
+	}
+	return
+}
 
-	s := bufio.NewScanner(bytes.NewReader(b))
-	for s.Scan() {
-		line := s.Text()
-		if match := commitRegexp.FindStringSubmatch(line); match != nil {
-			commits = append(commits, &Commit{
-				ID: match[1],
-			})
-		} else if match := treeRegexp.FindStringSubmatch(line); match != nil {
-			if len(commits) == 0 {
-				continue
-			}
-			commits[len(commits)-1].Tree = match[1]
-		} else if match := parentRegexp.FindStringSubmatch(line); match != nil {
-			if len(commits) == 0 {
-				continue
-			}
-			commits[len(commits)-1].Parent = match[1]
-		} else if match := authorRegexp.FindStringSubmatch(line); match != nil {
-			if len(commits) == 0 {
-				continue
-			}
-			i, err := strconv.ParseInt(match[3], 10, 64)
-			if err != nil {
-				continue
-			}
-			commits[len(commits)-1].Author = Author{
-				Name:  match[1],
-				Email: match[2],
+// GitMergeBase returns the commit where HEAD diverged from ref.
+func GitMergeBase(ctx context.Context, ref string) (string, error) {
+	b, err := exec.CommandContext(ctx, "git", "merge-base", ref, "HEAD").Output()
+	if e, ok := err.(*exec.ExitError); ok && e.ExitCode() == 1 {
+		return "", fmt.Errorf("%s and HEAD have no common ancestor", ref)
+	}
+	if err != nil {
+		return "", fmt.Errorf("git merge-base %s HEAD: %v", ref, err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// GitTagRange returns the revision range from tag from to tag to, or to HEAD
+// if to is empty, after checking that the tags exist and from is an ancestor
+// of to.
+func GitTagRange(ctx context.Context, from, to string) (string, error) {
+	ends := []string{"refs/tags/" + from, "HEAD"}
+	if to != "" {
+		ends[1] = "refs/tags/" + to
+	}
+	for _, ref := range ends {
+		err := exec.CommandContext(ctx, "git", "rev-parse", "--verify", "--quiet", ref+"^{commit}").Run()
+		if e, ok := err.(*exec.ExitError); ok && e.ExitCode() == 1 {
+			return "", fmt.Errorf("no tag %s", strings.TrimPrefix(ref, "refs/tags/"))
+		}
+		if err != nil {
+			return "", fmt.Errorf("git rev-parse %s: %v", ref, err)
+		}
+	}
+	err := exec.CommandContext(ctx, "git", "merge-base", "--is-ancestor", ends[0], ends[1]).Run()
+	if e, ok := err.(*exec.ExitError); ok && e.ExitCode() == 1 {
+		return "", fmt.Errorf("%s is not an ancestor of %s", from, strings.TrimPrefix(ends[1], "refs/tags/"))
+	}
+	if err != nil {
+		return "", fmt.Errorf("git merge-base --is-ancestor: %v", err)
+	}
+	return ends[0] + ".." + ends[1], nil
+}
+
+// ParseError is a part of git log output that cannot be parsed.
+type ParseError struct {
+	Kind string
+	Line string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %q", e.Kind, e.Line)
+}
+
+// ParseLog parses the output of git log with logFormat and --numstat. Parts
+// that cannot be parsed are skipped and returned as errs.
+func ParseLog(b []byte) (commits []*Commit, errs []*ParseError) {
+	for _, record := range strings.Split(string(b), "\x1e") {
+		if strings.TrimSpace(record) == "" {
+			continue
+		}
+		fields := strings.Split(record, "\x1f")
+		if len(fields) != 8 {
+			errs = append(errs, &ParseError{"bad commit record", record})
+			continue
+		}
+		commit := &Commit{
+			ID:   fields[0],
+			Tree: fields[1],
+		}
+		if parents := strings.Fields(fields[2]); len(parents) > 0 {
+			commit.Parent = parents[len(parents)-1]
+			commit.Parents = parents
+		}
+		if i, err := strconv.ParseInt(fields[5], 10, 64); err == nil {
+			commit.Author = Author{
+				Name:  fields[3],
+				Email: fields[4],
 				Time:  time.Unix(i, 0),
 			}
-		} else if match := messageRegexp.FindStringSubmatch(line); match != nil {
-			if len(commits) == 0 {
-				continue
+		} else {
+			errs = append(errs, &ParseError{"bad author time", fields[5]})
+		}
+		for _, line := range strings.Split(fields[6], "\n") {
+			if strings.TrimSpace(line) != "" {
+				commit.Message = append(commit.Message, line)
 			}
-			commits[len(commits)-1].Message = append(commits[len(commits)-1].Message, match[1])
-		} else if match := diffRegexp.FindStringSubmatch(line); match != nil {
-			if len(commits) == 0 {
-				continue
+		}
+		for _, line := range strings.Split(fields[7], "\n") {
+			if err := commit.addNumstat(line); err != nil {
+				errs = append(errs, err)
 			}
-			add, err := strconv.ParseInt(match[1], 10, 64)
-			if err != nil {
+		}
+		commits = append(commits, commit)
+	}
+	return
+}
+
+// addNumstat adds the diff of a --numstat line to commit. Blank lines and
+// binary files are skipped.
+func (commit *Commit) addNumstat(line string) *ParseError {
+	if strings.TrimSpace(line) == "" || binaryRegexp.MatchString(line) {
+		return nil
+	}
+	match := diffRegexp.FindStringSubmatch(line)
+	if match == nil {
+		return &ParseError{"bad numstat", line}
+	}
+	add, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return &ParseError{"bad numstat", line}
+	}
+	del, err := strconv.ParseInt(match[2], 10, 64)
+	if err != nil {
+		return &ParseError{"bad numstat", line}
+	}
+	from, file := splitRename(match[3])
+	commit.Diff = append(commit.Diff, Diff{
+		Add:    int(add),
+		Delete: int(del),
+		File:   file,
+		From:   from,
+	})
+	return nil
+}
+
+// unknownAuthor is the name given to commits without one by
+// -missing-author=unknown.
+const unknownAuthor = "unknown"
+
+// fixAuthors handles commits whose author could not be parsed. Commits
+// without an author time cannot be placed in time and are always dropped;
+// commits without a name are dropped, or named unknownAuthor with
+// -missing-author=unknown. Dropped commits are reported on stderr.
+func fixAuthors(commits []*Commit) []*Commit {
+	var kept []*Commit
+	var dropped []string
+	for _, commit := range commits {
+		if commit.Author.Name == "" && *missingAuthor == "unknown" {
+			commit.Author.Name = unknownAuthor
+		}
+		if commit.Author.Name == "" || commit.Author.Time.IsZero() {
+			dropped = append(dropped, shortID(commit.ID))
+			continue
+		}
+		kept = append(kept, commit)
+	}
+	if len(dropped) > 0 {
+		fmt.Fprintf(os.Stderr, "skipping %d commits without an author name or time: %s\n",
+			len(dropped), strings.Join(dropped, " "))
+	}
+	return kept
+}
+
+// excludeAuthors drops commits whose author name or email contains any of
+// the patterns, ignoring case.
+func excludeAuthors(commits []*Commit, patterns []string) []*Commit {
+	var kept []*Commit
+	for _, commit := range commits {
+		name := strings.ToLower(commit.Author.Name)
+		email := strings.ToLower(commit.Author.Email)
+		excluded := false
+		for _, p := range patterns {
+			p = strings.ToLower(strings.TrimSpace(p))
+			if p == "" {
 				continue
 			}
-			del, err := strconv.ParseInt(match[2], 10, 64)
-			if err != nil {
-				continue
+			if strings.Contains(name, p) || strings.Contains(email, p) {
+				excluded = true
+				break
 			}
-			commits[len(commits)-1].Diff = append(commits[len(commits)-1].Diff, Diff{
-				Add:    int(add),
-				Delete: int(del),
-				File:   match[3],
-			})
+		}
+		if !excluded {
+			kept = append(kept, commit)
 		}
 	}
-	return
+	return kept
 }
 
 var (
-	addRegexp        = regexp.MustCompile(`^\+([^+].*)$`)
-	delRegexp        = regexp.MustCompile(`^\-([^-].*)$`)
+	oldFileRegexp    = regexp.MustCompile(`^--- a/(.+)$`)
+	newFileRegexp    = regexp.MustCompile(`^\+\+\+ b/(.+)$`)
 	usefulLineRegexp = regexp.MustCompile(`(?:[a-zA-Z0-9_]+\(|^if |^for |=)`)
 )
 
-func GitDiff(commitID string) (add, del []string, err error) {
-	b, err := exec.Command("git", "diff", commitID+"^!").Output()
+type DiffLine struct {
+	File string
+	Line string
+}
+
+// Hunk is the range of lines changed by a hunk, in the new version of File.
+type Hunk struct {
+	File  string
+	Start int
+	Lines int
+}
+
+// Patch is the useful part of a commit's diff.
+type Patch struct {
+	Add   []DiffLine
+	Del   []DiffLine
+	Hunks []Hunk
+
+	// API counts changed lines per Go file that declare exported
+	// identifiers.
+	API map[string]int
+	// Code counts changed lines per file that are neither blank nor
+	// comments.
+	Code map[string]int
+}
+
+var diffCache = make(map[string]*Patch)
+
+// GitDiff returns useful lines added and deleted by a commit. Merge commits
+// are diffed against their first parent. Results are cached by commit.
+func GitDiff(ctx context.Context, commitID string) (*Patch, error) {
+	return gitDiff(ctx, commitID, diffCache, UsefulLine)
+}
+
+// NewGitDiff returns a GitDiff that keeps the changed lines for which keep
+// returns true. It has its own cache.
+func NewGitDiff(keep LineFilter) DiffFunc {
+	cache := make(map[string]*Patch)
+	return func(ctx context.Context, commitID string) (*Patch, error) {
+		return gitDiff(ctx, commitID, cache, keep)
+	}
+}
+
+func gitDiff(ctx context.Context, commitID string, cache map[string]*Patch, keep LineFilter) (*Patch, error) {
+	if p, ok := cache[commitID]; ok {
+		return p, nil
+	}
+	b, err := exec.CommandContext(ctx, "git", "-c", "core.quotepath=false", "show", "--format=", "--diff-merges=first-parent", commitID).Output()
 	if err != nil {
-		return
+		return nil, err
+	}
+	p := ParseDiff(b, keep)
+	cache[commitID] = p
+	return p, nil
+}
+
+// LineFilter reports whether a changed line, trimmed of space, is useful.
+type LineFilter func(line string) bool
+
+// UsefulLine is the default LineFilter. It skips comments, lines shorter than
+// -min-line-length and lines without a call, an assignment or control flow.
+func UsefulLine(line string) bool {
+	return isCode(line) && utf8.RuneCountInString(line) >= *minLineLength && usefulLineRegexp.MatchString(line)
+}
+
+// isCode reports whether a trimmed line is neither blank nor a comment.
+func isCode(line string) bool {
+	return line != "" && !strings.HasPrefix(line, "/") && !strings.HasPrefix(line, "*")
+}
+
+var hunkRegexp = regexp.MustCompile(`^@@ -[0-9]+(?:,([0-9]+))? \+([0-9]+)(?:,([0-9]+))? @@`)
+
+// ParseDiff extracts added and deleted lines kept by keep, and hunk ranges,
+// from a unified diff. A nil keep is UsefulLine.
+func ParseDiff(b []byte, keep LineFilter) *Patch {
+	if keep == nil {
+		keep = UsefulLine
 	}
+	p := &Patch{API: make(map[string]int), Code: make(map[string]int)}
+	var file string
+	// lines left in the current hunk, so that changed lines starting with
+	// ++ or -- are not mistaken for file headers
+	var oldLeft, newLeft int
 	s := bufio.NewScanner(bytes.NewReader(b))
 	for s.Scan() {
 		line := s.Text()
-		if match := addRegexp.FindStringSubmatch(line); match != nil {
-			s := strings.TrimSpace(match[1])
-			// ignore comments
-			if strings.HasPrefix(s, "/") || strings.HasPrefix(s, "*") {
+		if oldLeft > 0 || newLeft > 0 {
+			var lines *[]DiffLine
+			switch {
+			case strings.HasPrefix(line, "+"):
+				newLeft--
+				lines = &p.Add
+			case strings.HasPrefix(line, "-"):
+				oldLeft--
+				lines = &p.Del
+			case strings.HasPrefix(line, `\`):
+				// no newline at end of file
 				continue
-			}
-			if !usefulLineRegexp.MatchString(s) {
+			default:
+				oldLeft--
+				newLeft--
 				continue
 			}
-			add = append(add, s)
-		} else if match := delRegexp.FindStringSubmatch(line); match != nil {
-			s := strings.TrimSpace(match[1])
-			// ignore comments
-			if strings.HasPrefix(s, "/") || strings.HasPrefix(s, "*") {
+			if strings.HasPrefix(line[1:], "Subproject commit ") {
+				// submodule pointer bump
 				continue
 			}
-			if !usefulLineRegexp.MatchString(s) {
-				continue
+			s := strings.TrimSpace(line[1:])
+			if isAPI(file, s) {
+				p.API[file]++
+			}
+			if isCode(s) {
+				p.Code[file]++
+			}
+			if keep(s) {
+				*lines = append(*lines, DiffLine{File: file, Line: s})
 			}
-			del = append(del, s)
+			continue
+		}
+		if match := oldFileRegexp.FindStringSubmatch(line); match != nil {
+			file = match[1]
+		} else if match := newFileRegexp.FindStringSubmatch(line); match != nil {
+			file = match[1]
+		} else if match := hunkRegexp.FindStringSubmatch(line); match != nil {
+			start, _ := strconv.Atoi(match[2])
+			p.Hunks = append(p.Hunks, Hunk{
+				File:  file,
+				Start: start,
+				Lines: hunkLen(match[3]),
+			})
+			oldLeft, newLeft = hunkLen(match[1]), hunkLen(match[3])
 		}
 	}
-	return
+	return p
+}
+
+func hunkLen(s string) int {
+	if s == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func hasExt(file string, exts []string) bool {
+	for _, ext := range exts {
+		if ext != "" && strings.HasSuffix(file, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// GitSubmodules returns the paths of submodules listed in .gitmodules.
+func GitSubmodules(ctx context.Context) map[string]bool {
+	paths := make(map[string]bool)
+	b, err := exec.CommandContext(ctx, "git", "config", "--file", ".gitmodules",
+		"--get-regexp", `^submodule\..*\.path$`).Output()
+	if err != nil {
+		return paths
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			paths[line[i+1:]] = true
+		}
+	}
+	return paths
+}
+
+// GitLsTree returns the files in the tree of ref.
+func GitLsTree(ctx context.Context, ref string) (map[string]bool, error) {
+	b, err := exec.CommandContext(ctx, "git", "ls-tree", "-r", "--name-only", "-z", ref).Output()
+	if err != nil {
+		if e, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("git ls-tree: %s", bytes.TrimSpace(e.Stderr))
+		}
+		return nil, err
+	}
+	files := make(map[string]bool)
+	for _, file := range strings.Split(string(b), "\x00") {
+		if file != "" {
+			files[file] = true
+		}
+	}
+	return files, nil
+}
+
+var grepFiles map[string]bool
+
+// GitGrep returns the files at HEAD that match the pattern. The result is
+// cached.
+func GitGrep(ctx context.Context, pattern string) (map[string]bool, error) {
+	if grepFiles != nil {
+		return grepFiles, nil
+	}
+	files := make(map[string]bool)
+	b, err := exec.CommandContext(ctx, "git", "-c", "core.quotepath=false", "grep", "-l", "-E", "-e", pattern, "HEAD").Output()
+	if err != nil {
+		// exit status 1 means nothing matches
+		e, ok := err.(*exec.ExitError)
+		if !ok {
+			return nil, err
+		}
+		if e.ExitCode() != 1 {
+			return nil, fmt.Errorf("git grep: %s", bytes.TrimSpace(e.Stderr))
+		}
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if line != "" {
+			files[strings.TrimPrefix(line, "HEAD:")] = true
+		}
+	}
+	grepFiles = files
+	return files, nil
+}
+
+var fileLines = make(map[string]int)
+
+// GitLineCount returns the number of lines of file at HEAD, or 0 if it does
+// not exist.
+func GitLineCount(ctx context.Context, file string) int {
+	if n, ok := fileLines[file]; ok {
+		return n
+	}
+	var n int
+	b, err := exec.CommandContext(ctx, "git", "show", "HEAD:"+file).Output()
+	if err == nil {
+		n = bytes.Count(b, []byte("\n"))
+	}
+	fileLines[file] = n
+	return n
+}
+
+// resetHeadCache drops cached results that depend on HEAD.
+func resetHeadCache() {
+	grepFiles = nil
+	fileComplexity = make(map[string]int)
+	fileLines = make(map[string]int)
+	fileBlame = make(map[string]map[string]int)
+}
+
+var fileAge = make(map[string]time.Duration)
+
+// GitFileAge returns how long ago the file was first added. It returns 0 if
+// no creation commit is found.
+func GitFileAge(ctx context.Context, file string) time.Duration {
+	if age, ok := fileAge[file]; ok {
+		return age
+	}
+	var age time.Duration
+	b, err := exec.CommandContext(ctx, "git", "log", "--diff-filter=A", "--follow",
+		"--format=%at", "--", file).Output()
+	if err == nil {
+		lines := strings.Fields(string(b))
+		if len(lines) > 0 {
+			i, err := strconv.ParseInt(lines[len(lines)-1], 10, 64)
+			if err == nil {
+				age = time.Since(time.Unix(i, 0))
+			}
+		}
+	}
+	fileAge[file] = age
+	return age
+}
+
+// age2boost turns file age into a score multiplier: 1 plus age in years.
+func age2boost(age time.Duration) float64 {
+	if age <= 0 {
+		return 1
+	}
+	return 1 + age.Hours()/24/365
+}
+
+// literalRegexp matches lines of composite literals, like test table
+// entries, that are data rather than code.
+var literalRegexp = regexp.MustCompile(`^(?:\{.*\},?|"[^"]*": .*,|[A-Za-z_][A-Za-z0-9_]*: [^=].*,)$`)
+
+// reasonGrepRegexp is -reason-grep, which reason lines must match.
+var reasonGrepRegexp *regexp.Regexp
+
+var (
+	spaceRegexp = regexp.MustCompile(`\s+`)
+	punctRegexp = regexp.MustCompile(` ?([(){}\[\],;]) ?`)
+)
+
+// reasonKey returns the key used to match a line across commits. With
+// -normalize-space, lines differing only in whitespace share the same key.
+func reasonKey(line string) string {
+	if !*normalizeSpace {
+		return line
+	}
+	line = spaceRegexp.ReplaceAllString(line, " ")
+	return punctRegexp.ReplaceAllString(line, "$1")
 }
 
 type Reason struct {
-	Line  string
-	Count int
+	Line  string `json:"line"`
+	Count int    `json:"count"`
+
+	// files changed by the line, for -systemic
+	files map[string]bool
 }
 
 type ByCount []*Reason
@@ -158,14 +595,236 @@ type ByCount []*Reason
 func (s ByCount) Len() int      { return len(s) }
 func (s ByCount) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
 func (s ByCount) Less(i, j int) bool {
-	return s[i].Count > s[j].Count
+	if s[i].Count != s[j].Count {
+		return s[i].Count > s[j].Count
+	}
+	return s[i].Line < s[j].Line
 }
 
 type Target struct {
-	Name   string
-	Commit []*Commit
-	Score  float64
-	Reason []*Reason
+	// Name joins Files with -group-separator.
+	Name   string    `json:"name"`
+	Files  []string  `json:"files"`
+	Commit []*Commit `json:"commit"`
+	Score  float64   `json:"score"`
+	Reason []*Reason `json:"reason"`
+
+	// HotFile:HotStart-HotEnd is the range changed the most often, which
+	// holds Concentration of all changed lines.
+	HotFile       string  `json:"hot_file,omitempty"`
+	HotStart      int     `json:"hot_start,omitempty"`
+	HotEnd        int     `json:"hot_end,omitempty"`
+	Concentration float64 `json:"concentration"`
+	// Entropy is how widely changes spread over lines, in bits: high for
+	// pervasive instability, low for a localized hot spot.
+	Entropy float64 `json:"entropy"`
+
+	// Owners counts commits by author, most active first. NewOwners is how
+	// many of them first committed in the last quarter of the target's
+	// history: a still growing set of owners means ownership is eroding.
+	Owners    []*Owner `json:"owners"`
+	NewOwners int      `json:"new_owners"`
+	// InactiveOwners are the main authors, set for -orphaned if none of
+	// them committed in the last quarter of the history.
+	InactiveOwners []string `json:"inactive_owners,omitempty"`
+
+	// Escaped is the path in -path that the file was renamed from.
+	Escaped string `json:"escaped,omitempty"`
+
+	// Contested counts reason lines that one author changed and another
+	// reverted, a sign that they overwrite each other.
+	Contested int `json:"contested_lines"`
+
+	// TestCommits counts commits that changed the test file too. With
+	// -test-rate, TestsStale flags targets whose tests rarely change along.
+	TestCommits int  `json:"test_commits,omitempty"`
+	TestsStale  bool `json:"tests_rarely_updated,omitempty"`
+
+	// Keywords are -keywords found in messages of the target's commits.
+	Keywords []string `json:"keywords,omitempty"`
+
+	// BlameOwner wrote BlameShare of the lines at HEAD, set for -blame.
+	BlameOwner string  `json:"blame_owner,omitempty"`
+	BlameShare float64 `json:"blame_share,omitempty"`
+
+	// Lines is the line count at HEAD, set for -quick-wins.
+	Lines int `json:"lines,omitempty"`
+
+	// RawScore is the score before -normalize.
+	RawScore float64 `json:"raw_score,omitempty"`
+
+	// Instability is the fraction of useful changed lines that were
+	// reverted by a later commit.
+	Instability float64 `json:"instability"`
+
+	// Velocity is score per day between the first and last commit, counting
+	// at least one day.
+	Velocity float64 `json:"velocity"`
+
+	// Add and Delete are the lines changed in the target's files. Skew is
+	// (Add-Delete)/(Add+Delete): -1 means only deletions, 1 only additions.
+	Add    int     `json:"add"`
+	Delete int     `json:"delete"`
+	Skew   float64 `json:"skew"`
+
+	// Review are the IDs of the fewest commits that make -review-share of
+	// the edit score.
+	Review []string `json:"review,omitempty"`
+
+	// for -explain
+	contrib   []float64
+	steps     [][]*fileStep
+	editScore float64
+	delta     int
+	external  bool
+}
+
+// Hotspot returns the file with the most edits in the target.
+func (t *Target) Hotspot() string {
+	if len(t.Files) == 1 {
+		return t.Files[0]
+	}
+	edit := make(map[string]int)
+	for _, commit := range t.Commit {
+		for _, diff := range commit.Diff {
+			edit[diff.File] += diff.Add + diff.Delete
+		}
+	}
+	var hotspot string
+	for _, file := range t.Files {
+		if hotspot == "" || edit[file] > edit[hotspot] {
+			hotspot = file
+		}
+	}
+	return hotspot
+}
+
+// anyFile reports whether any file of the target is in files.
+func (t *Target) anyFile(files map[string]bool) bool {
+	for _, file := range t.Files {
+		if files[file] {
+			return true
+		}
+	}
+	return false
+}
+
+type Owner struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type ByOwnerCount []*Owner
+
+func (s ByOwnerCount) Len() int      { return len(s) }
+func (s ByOwnerCount) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s ByOwnerCount) Less(i, j int) bool {
+	if s[i].Count != s[j].Count {
+		return s[i].Count > s[j].Count
+	}
+	return s[i].Name < s[j].Name
+}
+
+// countOwners counts commits of the target by author name.
+func (t *Target) countOwners() {
+	count := make(map[string]int)
+	since := make(map[string]time.Time)
+	for _, commit := range t.Commit {
+		count[commit.Author.Name]++
+		if s, ok := since[commit.Author.Name]; !ok || commit.Author.Time.Before(s) {
+			since[commit.Author.Name] = commit.Author.Time
+		}
+	}
+	first, last := t.timeRange()
+	recent := last.Add(-last.Sub(first) / 4)
+	t.Owners = nil
+	t.NewOwners = 0
+	for name, n := range count {
+		t.Owners = append(t.Owners, &Owner{Name: name, Count: n})
+		if len(count) > 1 && since[name].After(recent) {
+			t.NewOwners++
+		}
+	}
+	sort.Sort(ByOwnerCount(t.Owners))
+}
+
+// timeRange returns the author time of the first and last commit.
+func (t *Target) timeRange() (first, last time.Time) {
+	for _, commit := range t.Commit {
+		if first.IsZero() || commit.Author.Time.Before(first) {
+			first = commit.Author.Time
+		}
+		if last.IsZero() || commit.Author.Time.After(last) {
+			last = commit.Author.Time
+		}
+	}
+	return
+}
+
+// recentCommits returns the n most recent commits of the target in their
+// original order, or all of them if n is 0.
+func (t *Target) recentCommits(n int) []*Commit {
+	return mostRecent(t.Commit, n)
+}
+
+// mostRecent returns the n most recent of commits in their original order, or
+// all of them if n is 0.
+func mostRecent(all []*Commit, n int) []*Commit {
+	if n <= 0 || len(all) <= n {
+		return all
+	}
+	sorted := make([]*Commit, len(all))
+	copy(sorted, all)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Author.Time.After(sorted[j].Author.Time)
+	})
+	keep := make(map[*Commit]bool)
+	for _, commit := range sorted[:n] {
+		keep[commit] = true
+	}
+	var commits []*Commit
+	for _, commit := range all {
+		if keep[commit] {
+			commits = append(commits, commit)
+		}
+	}
+	return commits
+}
+
+// allFiles reports whether every file of the target is in files.
+func (t *Target) allFiles(files map[string]bool) bool {
+	for _, file := range t.Files {
+		if !files[file] {
+			return false
+		}
+	}
+	return true
+}
+
+// countEdits sums up added and deleted lines of the target's files.
+func (t *Target) countEdits() {
+	files := make(map[string]bool)
+	for _, file := range t.Files {
+		files[file] = true
+	}
+	t.Add, t.Delete = 0, 0
+	for _, commit := range t.Commit {
+		for _, diff := range commit.Diff {
+			if files[diff.File] {
+				t.Add += diff.Add
+				t.Delete += diff.Delete
+			}
+		}
+	}
+	if t.Add+t.Delete > 0 {
+		t.Skew = float64(t.Add-t.Delete) / float64(t.Add+t.Delete)
+	}
+}
+
+// isDeadCode reports whether a change is mostly deletions, which often means
+// dead code was removed.
+func isDeadCode(diff Diff) bool {
+	return *deadCodeRatio > 0 && float64(diff.Delete) > *deadCodeRatio*float64(diff.Add)
 }
 
 func edit2score(n int) (score float64) {
@@ -184,153 +843,1134 @@ type ByScore []*Target
 func (s ByScore) Len() int      { return len(s) }
 func (s ByScore) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
 func (s ByScore) Less(i, j int) bool {
-	return s[i].Score > s[j].Score ||
-		s[i].Score == s[j].Score && len(s[i].Commit) > len(s[j].Commit)
+	if s[i].Score != s[j].Score {
+		return s[i].Score > s[j].Score
+	}
+	if len(s[i].Commit) != len(s[j].Commit) {
+		return len(s[i].Commit) > len(s[j].Commit)
+	}
+	if s[i].Name != s[j].Name {
+		return s[i].Name < s[j].Name
+	}
+	return ByFiles(s).Less(i, j)
+}
+
+// ByFiles orders targets by their sorted set of files, which unlike names is
+// unique.
+type ByFiles []*Target
+
+func (s ByFiles) Len() int      { return len(s) }
+func (s ByFiles) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s ByFiles) Less(i, j int) bool {
+	a, b := s[i].Files, s[j].Files
+	for k := 0; k < len(a) && k < len(b); k++ {
+		if a[k] != b[k] {
+			return a[k] < b[k]
+		}
+	}
+	return len(a) < len(b)
+}
+
+type ByVelocity []*Target
+
+func (s ByVelocity) Len() int      { return len(s) }
+func (s ByVelocity) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s ByVelocity) Less(i, j int) bool {
+	if s[i].Velocity != s[j].Velocity {
+		return s[i].Velocity > s[j].Velocity
+	}
+	return ByScore(s).Less(i, j)
+}
+
+// velocity returns score per active day of t.
+func (t *Target) velocity() float64 {
+	first, last := t.timeRange()
+	return t.Score / math.Max(last.Sub(first).Hours()/24, 1)
 }
 
 var (
-	after     = flag.String("after", "1 week ago", "inspect commits after that time")
-	before    = flag.String("before", time.Now().Format(time.RFC3339), "inspect commits before that time")
-	topTarget = flag.Int("target", 10, "show top K targets")
-	topReason = flag.Int("reason", 3, "show top K reasons")
-	detail    = flag.Bool("detail", false, "show reason with only 1 count")
+	after             = flag.String("after", "1 week ago", "inspect commits after that time")
+	before            = flag.String("before", time.Now().Format(time.RFC3339), "inspect commits before that time")
+	topTarget         = flag.Int("target", 10, "show top K targets")
+	topReason         = flag.Int("reason", 3, "show top K reasons")
+	detail            = flag.Bool("detail", false, "show reason with only 1 count")
+	color             = flag.String("color", "auto", "colorize output: auto, always or never")
+	preferOld         = flag.Bool("prefer-old", false, "boost scores of older files")
+	limitReasons      = flag.Bool("limit-reasons-to-hotspots", false, "only collect reasons from the most edited file of a target")
+	tz                = flag.String("tz", "UTC", "time zone for displayed timestamps")
+	scorer            = flag.String("scorer", "", "external command that scores a target")
+	normalizeSpace    = flag.Bool("normalize-space", false, "treat reason lines differing only in whitespace as the same")
+	excludeAuthor     = flag.String("exclude-author", "", "skip commits by authors whose name or email contains any of these comma-separated patterns")
+	noReasons         = flag.Bool("no-reasons", false, "rank by edit distance only and skip the per-commit diff")
+	containsPattern   = flag.String("contains", "", "only show targets with a file matching this regexp at HEAD")
+	deadCodeRatio     = flag.Float64("dead-code-ratio", 0, "down-weight changes deleting more than this many times the lines they add; 0 disables")
+	deadCodeWeight    = flag.Float64("dead-code-weight", 0.5, "score multiplier for dead code removal")
+	explainTarget     = flag.String("explain", "", "print how the score of a target is computed")
+	patches           = flag.String("patches", "", "analyze a mbox or a directory of .patch files instead of git history")
+	format            = flag.String("format", "text", "output format: text, json, markdown, sarif or template")
+	diffReports       = flag.Bool("diff-reports", false, "compare two JSON reports given as arguments")
+	columnList        = flag.String("columns", "score,name,commits,owner", "comma-separated columns of text output")
+	includeSubmodules = flag.Bool("submodules", false, "include submodule pointer changes")
+	normalize         = flag.Bool("normalize", false, "rescale scores so that the top target is 100")
+	sqlitePath        = flag.String("sqlite", "", "append targets of this run to a SQLite database (needs sqlite3)")
+	strict            = flag.Bool("strict", false, "fail on git log output that cannot be parsed")
+	baseline          = flag.String("baseline", "", "only show targets whose files exist in this ref")
+	complexityWeight  = flag.Float64("complexity-weight", 0, "multiply scores of Go files by 1 + weight * cyclomatic complexity at HEAD")
+	watch             = flag.Bool("watch", false, "re-run whenever a ref changes")
+	watchInterval     = flag.Duration("watch-interval", 2*time.Second, "how often -watch checks refs")
+	skipLiterals      = flag.Bool("skip-literals", false, "ignore composite literal lines, like test table entries, in reasons")
+	minScoreDisplay   = flag.Float64("min-score-display", 0, "hide targets scoring below this in text output")
+	include           = flag.String("include", "", "only inspect files matching these comma-separated globs, or @file")
+	exclude           = flag.String("exclude", "", "skip files matching these comma-separated globs, or @file")
+	hotRange          = flag.Int("hot-range", 20, "size of the line range used to find where changes concentrate")
+	templateFile      = flag.String("template-file", "", "template for -format=template")
+	ext               = flag.String("ext", ".h,.c,.go", "comma-separated file extensions to inspect")
+	dir               = flag.String("dir", "", "git repository to inspect instead of the current directory")
+	serve             = flag.String("serve", "", "serve JSON reports over HTTP on this address, like :8080")
+	focusWeight       = flag.Float64("focus-weight", 0, "divide the score of each file in a commit by the commit's file count raised to this power")
+	foldCaseFlag      = flag.Bool("fold-case", false, "merge paths that differ only in case")
+	quickWins         = flag.Int("quick-wins", 0, "list top K small files with high churn per line")
+	commitsJSON       = flag.String("commits-json", "", "read commits from a JSON file instead of git log")
+	rewrite           = flag.String("rewrite", "", "comma-separated old=new path prefix substitutions applied before aggregation")
+	merge             = flag.Bool("merge", false, "combine JSON reports given as arguments into one ranking")
+	mergeMode         = flag.String("merge-mode", "prefix", "how -merge handles targets of the same name: prefix with the report name, or sum")
+	fixupCommits      = flag.Int("fixup-commits", 0, "list files changed again within N commits after a merge brought them in")
+	fixupWithin       = flag.Duration("fixup-within", 0, "list files changed again within this duration after a merge brought them in")
+	coverageProfile   = flag.String("coverage", "", "Go coverage profile; multiply scores by 1.5 - coverage of files in it")
+	groupSeparator    = flag.String("group-separator", ",", "separator between the files of a group target in its name")
+	reasonSample      = flag.Int("reason-sample", 0, "only diff the N most recent commits of a target for reasons; 0 diffs all")
+	blame             = flag.Bool("blame", false, "find who wrote most lines at HEAD of the shown targets")
+	filesOnly         = flag.Bool("files-only", false, "only show single-file targets, not groups")
+	keywords          = flag.String("keywords", "fix,hack,workaround,temporary,todo", "comma-separated words in commit messages that flag troubled code")
+	keywordWeight     = flag.Float64("keyword-weight", 1, "multiply scores of files changed by commits whose message has -keywords")
+	byHour            = flag.Bool("by-hour", false, "show how many files changed by commits of each hour of the day were changed again later")
+	bundle            = flag.String("bundle", "", "analyze a git bundle instead of a repository")
+	precision         = flag.Int("precision", 1, "decimal places of scores in text output")
+	systemic          = flag.Int("systemic", 0, "list reason lines that recur in at least N files")
+	staged            = flag.Bool("staged", false, "also inspect staged changes as if they were committed")
+	coupling          = flag.Int("coupling", 0, "list top K pairs of files by how likely changing one means changing the other")
+	couplingMin       = flag.Int("coupling-min", 2, "only list pairs of files that changed together at least this often")
+	baselineReport    = flag.String("baseline-report", "", "fail if targets regressed since this JSON report")
+	regressionDelta   = flag.Float64("regression-delta", 0, "score increase since -baseline-report that counts as a regression")
+	regressionFloor   = flag.Float64("regression-floor", 0, "score from which a target new since -baseline-report counts as a regression")
+	scope             = flag.String("path", "", "only inspect files in this directory, and files renamed out of it")
+	recent            = flag.Int("recent", 0, "only show targets whose files were changed by the N most recent commits")
+	matrix            = flag.Bool("matrix", false, "write which commits changed which files as CSV instead of ranking targets")
+	preferAPI         = flag.Bool("prefer-api", false, "boost scores of Go files whose exported declarations changed")
+	byWeekday         = flag.Bool("by-weekday", false, "show how many files changed by commits of each weekday were changed again later")
+	mergeBase         = flag.String("merge-base", "", "only inspect commits of HEAD since it diverged from this ref, instead of -after and -before")
+	ignoreDocs        = flag.Bool("ignore-docs", false, "skip commits that only change -doc-files or comments")
+	docFiles          = flag.String("doc-files", "*.md,*.rst,*.adoc,*.txt,doc,docs", "comma-separated globs of documentation files for -ignore-docs, or @file")
+	granularity       = flag.String("granularity", "file", "what a target is made of: file, or package for the directories of files")
+	labelsFile        = flag.String("labels", "", "file of commit IDs and their comma-separated labels, like exported issue labels")
+	labelWeightList   = flag.String("label-weights", "tech-debt=2,bug=1.5", "comma-separated label=weight score multipliers for commits with -labels")
+	showProgress      = flag.Bool("progress", false, "report how many targets are analyzed on stderr")
+	sarifError        = flag.Float64("sarif-error", 1000, "score from which -format=sarif reports a target as an error")
+	sarifWarning      = flag.Float64("sarif-warning", 100, "score from which -format=sarif reports a target as a warning rather than a note")
+	extWeightList     = flag.String("ext-weights", "", "comma-separated ext:weight score multipliers, like .go:1,.proto:2; only these extensions are inspected, instead of -ext")
+	check             = flag.Bool("check", false, "check that git works, the repository exists and the window has commits, instead of ranking targets")
+	respectRewrites   = flag.Bool("respect-rewrites", false, "ignore changes to a file before a commit that deleted nearly all of it")
+	budget            = flag.Int("budget", 0, "recommend N targets that together cover the most score of distinct files")
+	mailmap           = flag.Bool("mailmap", true, "map commit authors with .mailmap, as git blame always does")
+	reasonGrep        = flag.String("reason-grep", "", "only find reasons in changed lines matching this regexp, like an identifier")
+	globalReasons     = flag.Int("global-reasons", 0, "list top K reason lines summed across all files")
+	testRate          = flag.Float64("test-rate", 0, "flag targets whose test files changed in fewer than this share of their commits; 0 disables")
+	testFiles         = flag.String("test-files", "{dir}/{base}_test{ext},{dir}/test_{base}{ext},{dir}/{base}.test{ext},{dir}/{base}.spec{ext}", "comma-separated paths of the tests of a file for -test-rate, made of its {dir}, {base} and {ext}")
+	gzipOutput        = flag.Bool("gzip", false, "gzip compress the output")
+	sortBy            = flag.String("sort", "score", "rank targets by score, or by velocity: score per active day")
+	logFile           = flag.String("log-file", "", "read commits from a saved git log --format=raw --numstat instead of running it")
+	diffDir           = flag.String("diff-dir", "", "read the diff of each commit from <commit>.diff in this directory instead of git show")
+	squashWindow      = flag.Duration("squash-window", 0, "treat consecutive commits by the same author within this duration of each other as one change")
+	orphaned          = flag.Bool("orphaned", false, "warn about targets whose main authors made no commits in the last quarter of the history")
+	groupAgg          = flag.String("group-agg", "sum", "how a commit scores a group of files: sum of file scores times file count, or max file score")
+	includeImport     = flag.Bool("include-import", false, "keep the root commit and commits changing an outlier number of files, like initial imports and vendoring")
+	minLineLength     = flag.Int("min-line-length", 3, "ignore changed lines shorter than this, after trimming space, in reasons")
+	windows           = flag.String("windows", "", "write JSON reports of N consecutive windows of days or weeks before -before, like 12w")
+	configFiles       = flag.String("config-files", "go.mod,go.sum,package.json,package-lock.json,yarn.lock,pnpm-lock.yaml,Cargo.lock,Gemfile.lock,poetry.lock,*.yml,*.yaml,*.toml,*.ini", "comma-separated globs of config and lock files whose changes of mostly settings are not ranked, or @file")
+	scoreBy           = flag.String("score-by", "lines", "what scores a change to a file: lines, the number of digits of changed lines; hunks, the number of hunks; or both multiplied")
+	fromTag           = flag.String("from-tag", "", "only inspect commits after this tag, up to -to-tag, instead of -after and -before")
+	toTag             = flag.String("to-tag", "", "with -from-tag, only inspect commits up to this tag instead of HEAD")
+	reviewShare       = flag.Float64("review-share", 0.8, "list the fewest commits of a target making this share of its edit score in detail output; 0 disables")
+	missingAuthor     = flag.String("missing-author", "skip", "what to do with commits without an author name: skip, or count them as unknown; commits without an author time are always skipped")
 )
 
+var location = time.UTC
+
+func formatTime(t time.Time) string {
+	return t.In(location).Format("2006-01-02 15:04")
+}
+
+// DiffFunc returns the patch of a commit, like GitDiff.
+type DiffFunc func(ctx context.Context, commitID string) (*Patch, error)
+
+// findReasons collects lines that are added and deleted back and forth
+// across the commits of t.
+func findReasons(ctx context.Context, t *Target, diff DiffFunc) {
+	// diff analysis
+	plus := make(map[string]*Commit)
+	minus := make(map[string]*Commit)
+	delta := make(map[string]int)
+	// lines whose change was reverted by another author
+	contested := make(map[string]bool)
+	display := make(map[string]string)
+	files := make(map[string]map[string]bool)
+	var lines int
+
+	var hotspot string
+	if *limitReasons {
+		hotspot = t.Hotspot()
+	}
+	var hunks []Hunk
+	for _, commit := range t.recentCommits(*reasonSample) {
+		p, err := diff(ctx, commit.ID)
+		if err != nil {
+			continue
+		}
+		for _, h := range p.Hunks {
+			h.File = canonicalFile(h.File)
+			hunks = append(hunks, h)
+		}
+		for _, l := range p.Add {
+			if hotspot != "" && packageOf(canonicalFile(l.File)) != hotspot {
+				continue
+			}
+			if *skipLiterals && literalRegexp.MatchString(l.Line) {
+				continue
+			}
+			if reasonGrepRegexp != nil && !reasonGrepRegexp.MatchString(l.Line) {
+				continue
+			}
+			lines++
+			line := reasonKey(l.Line)
+			if _, ok := display[line]; !ok {
+				display[line] = l.Line
+				files[line] = make(map[string]bool)
+			}
+			files[line][canonicalFile(l.File)] = true
+			if c, ok := minus[line]; ok && c.ID != commit.ID {
+				delta[line]++
+				if c.Author.Name != commit.Author.Name {
+					contested[line] = true
+				}
+				delete(minus, line)
+			}
+			plus[line] = commit
+		}
+		for _, l := range p.Del {
+			if hotspot != "" && packageOf(canonicalFile(l.File)) != hotspot {
+				continue
+			}
+			if *skipLiterals && literalRegexp.MatchString(l.Line) {
+				continue
+			}
+			if reasonGrepRegexp != nil && !reasonGrepRegexp.MatchString(l.Line) {
+				continue
+			}
+			lines++
+			line := reasonKey(l.Line)
+			if _, ok := display[line]; !ok {
+				display[line] = l.Line
+				files[line] = make(map[string]bool)
+			}
+			files[line][canonicalFile(l.File)] = true
+			if c, ok := plus[line]; ok && c.ID != commit.ID {
+				delta[line]++
+				if c.Author.Name != commit.Author.Name {
+					contested[line] = true
+				}
+				delete(plus, line)
+			}
+			minus[line] = commit
+		}
+	}
+	var total int
+	for line, count := range delta {
+		t.Reason = append(t.Reason, &Reason{
+			Line:  display[line],
+			Count: count,
+			files: files[line],
+		})
+		total += count
+	}
+	sort.Sort(ByCount(t.Reason))
+	if lines > 0 {
+		t.Instability = math.Min(float64(2*total)/float64(lines), 1)
+	}
+	t.delta = total
+	t.Contested = len(contested)
+	t.findHotRange(hunks)
+}
+
 func main() {
 	flag.Parse()
 
-	commits, err := GitLog()
+	switch *format {
+	case "text", "json", "markdown", "sarif":
+	case "template":
+		if *templateFile == "" {
+			fmt.Fprintln(os.Stderr, "-format=template needs -template-file")
+			os.Exit(2)
+		}
+		if err := parseTemplate(*templateFile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "invalid -format %q: must be text, json, markdown, sarif or template\n", *format)
+		os.Exit(2)
+	}
+	if *gzipOutput {
+		if err := gzipStdout(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+	if *diffReports {
+		if flag.NArg() != 2 {
+			fmt.Fprintln(os.Stderr, "-diff-reports needs two JSON reports: old and new")
+			exit(2)
+		}
+		if err := DiffReports(os.Stdout, flag.Arg(0), flag.Arg(1)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(1)
+		}
+		exit(0)
+	}
+	var err error
+	if *precision < 0 {
+		fmt.Fprintln(os.Stderr, "-precision must not be negative")
+		exit(2)
+	}
+	if err := parseColumns(*columnList); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(2)
+	}
+	includePatterns, err = parsePatterns(*include)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(2)
+	}
+	excludePatterns, err = parsePatterns(*exclude)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(2)
+	}
+	if err := parseCoverage(*coverageProfile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(2)
+	}
+	docPatterns, err = parsePatterns(*docFiles)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(2)
+	}
+	switch *granularity {
+	case "file", "package":
+	default:
+		fmt.Fprintf(os.Stderr, "invalid -granularity %q: must be file or package\n", *granularity)
+		exit(2)
+	}
+	if *reasonGrep != "" {
+		var err error
+		reasonGrepRegexp, err = regexp.Compile(*reasonGrep)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -reason-grep: %v\n", err)
+			exit(2)
+		}
+	}
+	testTemplates = strings.Split(*testFiles, ",")
+	configPatterns, err = parsePatterns(*configFiles)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(2)
+	}
+	if *toTag != "" && *fromTag == "" {
+		fmt.Fprintln(os.Stderr, "-to-tag needs -from-tag")
+		exit(2)
+	}
+	if *fromTag != "" && (*mergeBase != "" || *windows != "") {
+		fmt.Fprintln(os.Stderr, "-from-tag cannot be used with -merge-base or -windows")
+		exit(2)
+	}
+	if *windows != "" {
+		if _, _, err := parseWindows(*windows); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(2)
+		}
+		if *mergeBase != "" {
+			fmt.Fprintln(os.Stderr, "-windows and -merge-base cannot be used together")
+			exit(2)
+		}
+	}
+	switch *missingAuthor {
+	case "skip", "unknown":
+	default:
+		fmt.Fprintf(os.Stderr, "invalid -missing-author %q: must be skip or unknown\n", *missingAuthor)
+		exit(2)
+	}
+	switch *scoreBy {
+	case "lines", "hunks", "both":
+	default:
+		fmt.Fprintf(os.Stderr, "invalid -score-by %q: must be lines, hunks or both\n", *scoreBy)
+		exit(2)
+	}
+	switch *groupAgg {
+	case "sum", "max":
+	default:
+		fmt.Fprintf(os.Stderr, "invalid -group-agg %q: must be sum or max\n", *groupAgg)
+		exit(2)
+	}
+	if *sqlitePath != "" {
+		// fail before inspecting the history, not after
+		if _, err := exec.LookPath("sqlite3"); err != nil {
+			fmt.Fprintf(os.Stderr, "-sqlite needs the sqlite3 command: %v\n", err)
+			exit(2)
+		}
+	}
+	switch *sortBy {
+	case "score", "velocity":
+	default:
+		fmt.Fprintf(os.Stderr, "invalid -sort %q: must be score or velocity\n", *sortBy)
+		exit(2)
+	}
+	if err := parseLabels(*labelsFile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(2)
+	}
+	if err := parseExtWeights(*extWeightList); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(2)
+	}
+	if err := parseLabelWeights(*labelWeightList); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(2)
+	}
+	if err := parseKeywords(*keywords); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(2)
+	}
+	if err := parseRewrite(*rewrite); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(2)
+	}
+	if err := setupColor(*color); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(2)
+	}
+	location, err = time.LoadLocation(*tz)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(2)
+	}
+
+	if *merge {
+		if flag.NArg() == 0 {
+			fmt.Fprintln(os.Stderr, "-merge needs JSON reports as arguments")
+			exit(2)
+		}
+		if err := parseMergeMode(*mergeMode); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(2)
+		}
+		r, err := MergeReports(flag.Args(), *mergeMode)
+		if err == nil {
+			if *format == "json" {
+				r.Manifest = newManifest(context.Background())
+			}
+			err = writeReport(r)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if *dir != "" && *bundle != "" {
+		fmt.Fprintln(os.Stderr, "-dir and -bundle cannot be used together")
+		exit(2)
+	}
+	if *dir != "" {
+		if err := os.Chdir(*dir); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(2)
+		}
+	}
+
+	// cancel on interrupt so that git subprocesses are killed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = start(ctx)
+	if ctx.Err() != nil {
+		stop()
+		fmt.Fprintln(os.Stderr, "interrupted")
+		exit(130)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(1)
+	}
+	exit(0)
+}
+
+// start runs in the mode selected by flags.
+func start(ctx context.Context) error {
+	if *bundle != "" {
+		repo, err := CloneBundle(ctx, *bundle)
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(repo)
+		if err := os.Chdir(repo); err != nil {
+			return err
+		}
+	}
+	if *check {
+		return Check(ctx, os.Stdout)
+	}
+	if *windows != "" {
+		return WriteWindows(ctx, os.Stdout)
+	}
+	if *matrix {
+		return WriteMatrix(ctx, os.Stdout)
+	}
+	if *serve != "" {
+		return Serve(ctx, *serve)
+	}
+	if *watch {
+		return Watch(ctx, *watchInterval)
+	}
+	return run(ctx)
+}
+
+// run analyzes the history once and prints the report.
+func run(ctx context.Context) error {
+	a, err := Analyze(ctx)
+	if err != nil {
+		return err
+	}
+
+	if *explainTarget != "" {
+		t, err := a.Find(*explainTarget)
+		if err != nil {
+			return err
+		}
+		explain(t)
+		return nil
+	}
+
+	if *sqlitePath != "" {
+		err = WriteSQLite(ctx, *sqlitePath, a.Targets, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+
+	report := a.Report()
+	if *blame {
+		findBlameOwners(ctx, report.Targets)
+	}
+	if *testRate > 0 {
+		if err := findStaleTests(ctx, report.Targets); err != nil {
+			return err
+		}
+	}
+	if *quickWins > 0 {
+		report.QuickWins = findQuickWins(ctx, a.Targets, *quickWins)
+	}
+	if *budget > 0 {
+		report.Budget = planBudget(a.Targets, *budget)
+	}
+	if *baselineReport != "" {
+		old, err := readReport(*baselineReport)
+		if err != nil {
+			return err
+		}
+		report.Regressions = findRegressions(old, &Report{Targets: a.Targets})
+	}
+	if *systemic > 0 {
+		report.Systemic = findSystemicReasons(a.Targets, *systemic, *topTarget)
+	}
+	if *globalReasons > 0 {
+		report.Global = findGlobalReasons(a.Targets, *globalReasons)
+	}
+	if *coupling > 0 {
+		report.Coupling = findCoupling(a.Commits, *couplingMin, *coupling)
+	}
+	if *byHour {
+		report.ByHour = countByHour(a.Commits)
+	}
+	if *byWeekday {
+		report.ByWeekday = countByWeekday(a.Commits)
+	}
+	if *fixupCommits > 0 || *fixupWithin > 0 {
+		report.Fixups, err = findFixups(ctx, a.Commits, *topTarget)
+		if err != nil {
+			return err
+		}
+	}
+	if *format == "json" {
+		report.Manifest = newManifest(ctx)
+	}
+	if err := writeReport(report); err != nil {
+		return err
+	}
+	if len(report.Regressions) > 0 {
+		return fmt.Errorf("%d targets regressed since %s", len(report.Regressions), *baselineReport)
+	}
+	return nil
+}
+
+// writeReport prints r to stdout in -format.
+func writeReport(r *Report) error {
+	switch *format {
+	case "json":
+		return writeJSON(os.Stdout, r)
+	case "markdown":
+		return writeMarkdown(os.Stdout, r)
+	case "sarif":
+		return writeSARIF(os.Stdout, r)
+	case "template":
+		return writeTemplate(os.Stdout, r)
+	default:
+		printText(r)
+	}
+	return nil
+}
+
+// Analysis is the result of inspecting the history.
+type Analysis struct {
+	// Targets are ranked by score.
+	Targets []*Target
+	// All has every target, including the ones scoring 0, sorted by files.
+	All     []*Target
+	Commits []*Commit
+	// Drift is the churn of config files left out of targets.
+	Drift *ConfigDrift
+}
+
+// Find returns the target named name. Names are files joined by
+// -group-separator, so a file whose name contains the separator and a group
+// can share a name; such a name is an error that lists the file sets.
+func (a *Analysis) Find(name string) (*Target, error) {
+	var found []*Target
+	for _, t := range a.All {
+		if t.Name == name {
+			found = append(found, t)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("no target %q", name)
+	case 1:
+		return found[0], nil
+	}
+	var sets []string
+	for _, t := range found {
+		var files []string
+		for _, file := range t.Files {
+			files = append(files, strconv.Quote(file))
+		}
+		sets = append(sets, "["+strings.Join(files, " ")+"]")
+	}
+	return nil, fmt.Errorf("target %q is ambiguous: it names %s", name, strings.Join(sets, " and "))
+}
+
+// Report returns the top K targets.
+func (a *Analysis) Report() *Report {
+	targets := a.Targets
+	if *filesOnly {
+		targets = nil
+		for _, t := range a.Targets {
+			if len(t.Files) == 1 {
+				targets = append(targets, t)
+			}
+		}
+	}
+	r := &Report{
+		Targets:      targets,
+		TotalTargets: len(targets),
+		TotalCommits: len(a.Commits),
+		Stats:        computeStats(targets),
+	}
+	if a.Drift != nil && a.Drift.Lines > 0 {
+		r.Drift = a.Drift
+	}
+	if len(r.Targets) > *topTarget {
+		r.Targets = r.Targets[:*topTarget]
+	}
+	return r
+}
+
+// loadCommits reads commits from the source selected by flags, with file
+// names normalized, and returns how to diff them.
+func loadCommits(ctx context.Context) (commits []*Commit, diff DiffFunc, err error) {
+	diff = GitDiff
+	if *patches != "" {
+		commits, err = ReadPatches(*patches)
+		diff = PatchDiff
+	} else if *commitsJSON != "" {
+		commits, err = ReadCommits(*commitsJSON)
+	} else if *logFile != "" {
+		commits, err = ReadLogFile(*logFile)
+	} else {
+		commits, err = GitLog(ctx)
+	}
 	if err != nil {
 		return
 	}
-	m := make(map[string]*Target)
-	add := func(name string, commit *Commit, score float64) {
-		if t, ok := m[name]; ok {
+	if *diffDir != "" {
+		diff = DirDiff
+	}
+	if *patches == "" && *commitsJSON == "" {
+		// plain patches have no author, only git logs can be checked
+		commits = fixAuthors(commits)
+	}
+	if *staged {
+		var commit *Commit
+		commit, err = GitStaged(ctx)
+		if err != nil {
+			return
+		}
+		if commit != nil {
+			commits = append([]*Commit{commit}, commits...)
+		}
+	}
+	for _, commit := range commits {
+		commit.parseSubject()
+	}
+	renameFiles(ctx, commits)
+	findEscaped(commits)
+	return
+}
+
+// Analyze inspects the history and ranks targets.
+func Analyze(ctx context.Context) (*Analysis, error) {
+	commits, diff, err := loadCommits(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return analyzeCommits(ctx, commits, diff)
+}
+
+// analyzeCommits ranks targets of loaded commits.
+func analyzeCommits(ctx context.Context, commits []*Commit, diff DiffFunc) (*Analysis, error) {
+	var err error
+	if !*includeImport {
+		// only git history tells which commits are roots
+		commits = dropImportCommits(commits, *patches == "" && *commitsJSON == "")
+	}
+	if *ignoreDocs {
+		commits = dropDocCommits(ctx, commits, diff)
+	}
+	// quick fix-up commits are part of the same change
+	if *squashWindow > 0 {
+		commits = squashCommits(commits, *squashWindow)
+		diff = squashDiff(commits, diff)
+	}
+	// churn before a rewrite is about code that no longer exists
+	var rewrites map[string]time.Time
+	if *respectRewrites {
+		rewrites = findRewrites(ctx, commits)
+	}
+	// mechanical changes of config files are counted apart
+	drift := new(ConfigDrift)
+	driftFiles := make(map[string]bool)
+	var churn int
+
+	// targets are keyed by a hash of their sorted set of files, which stays
+	// small for commits touching many files
+	m := make(map[[sha256.Size]byte]*Target)
+	add := func(files []string, commit *Commit, score float64, steps []*fileStep) {
+		files = append([]string(nil), files...)
+		sort.Strings(files)
+		key := sha256.Sum256([]byte(strings.Join(files, "\x00")))
+		if t, ok := m[key]; ok {
 			t.Commit = append(t.Commit, commit)
 			t.Score += score
+			t.contrib = append(t.contrib, score)
+			t.steps = append(t.steps, steps)
 		} else {
-			m[name] = &Target{
-				Name:   name,
-				Score:  score,
-				Commit: []*Commit{commit},
+			m[key] = &Target{
+				Name:    strings.Join(files, *groupSeparator),
+				Files:   files,
+				Score:   score,
+				Commit:  []*Commit{commit},
+				contrib: []float64{score},
+				steps:   [][]*fileStep{steps},
 			}
 		}
 	}
+	exts := fileExts()
+	var submodules map[string]bool
+	if !*includeSubmodules {
+		submodules = GitSubmodules(ctx)
+	}
 	for _, commit := range commits {
 		var files []string
 		var score float64
+		var diffs []Diff
 		for _, diff := range commit.Diff {
+			if submodules[diff.File] || !keepFile(diff.File) || !hasExt(diff.File, exts) || !inScope(diff.File) {
+				continue
+			}
+			if t, ok := rewrites[diff.File]; ok && commit.Author.Time.Before(t) {
+				continue
+			}
+			churn += diff.Add + diff.Delete
+			if len(configPatterns) > 0 && isConfigDrift(ctx, commit, diff) {
+				drift.Lines += diff.Add + diff.Delete
+				driftFiles[diff.File] = true
+				continue
+			}
+			diffs = append(diffs, diff)
+		}
+		if *granularity == "package" {
+			// targets are directories from here on
+			diffs = groupByPackage(diffs)
+			commit.Diff = diffs
+		}
+		// developers flag troubled code in messages
+		keyword, label := 1.0, 1.0
+		if keywordRegexp != nil && len(commitKeywords(commit)) > 0 {
+			keyword = *keywordWeight
+		}
+		if commitLabels != nil {
+			label = labelBoost(commit)
+		}
+		boost := keyword * label
+		var api map[string]int
+		if *preferAPI {
+			api = commitAPI(ctx, commit, diff)
+		}
+		var hunks map[string]int
+		if *scoreBy != "lines" {
+			hunks = commitHunks(ctx, commit, diff)
+		}
+		// focused commits touch fewer files
+		focus := 1.0
+		if len(diffs) > 0 {
+			focus = 1 / math.Pow(float64(len(diffs)), *focusWeight)
+		}
+		var steps []*fileStep
+		for _, diff := range diffs {
 			// per-file
-			if strings.HasSuffix(diff.File, ".h") ||
-				strings.HasSuffix(diff.File, ".c") ||
-				strings.HasSuffix(diff.File, ".go") {
-
-				fileScore := edit2score(diff.Add + diff.Delete)
+			edit := edit2score(diff.Add + diff.Delete)
+			// scattered changes may matter more than many changed lines
+			switch *scoreBy {
+			case "hunks":
+				edit = float64(hunks[diff.File])
+			case "both":
+				edit *= float64(hunks[diff.File])
+			}
+			fileScore := edit * focus * boost
+			factors := addFactor(nil, "focus", focus)
+			factors = addFactor(factors, "keyword", keyword)
+			factors = addFactor(factors, "label", label)
+			if extWeights != nil {
+				f := extWeight(diff.File)
+				fileScore *= f
+				factors = addFactor(factors, "ext", f)
+			}
+			if *preferOld {
+				f := age2boost(GitFileAge(ctx, diff.File))
+				fileScore *= f
+				factors = addFactor(factors, "age", f)
+			}
+			if api != nil {
+				f := api2boost(api[diff.File])
+				fileScore *= f
+				factors = addFactor(factors, "api", f)
+			}
+			if *complexityWeight > 0 {
+				f := 1 + *complexityWeight*float64(GitComplexity(ctx, diff.File))
+				fileScore *= f
+				factors = addFactor(factors, "complexity", f)
+			}
+			if coverage != nil {
+				f := coverage2boost(diff.File)
+				fileScore *= f
+				factors = addFactor(factors, "coverage", f)
+			}
+			if isDeadCode(diff) {
+				fileScore *= *deadCodeWeight
+				factors = addFactor(factors, "dead code", *deadCodeWeight)
+			}
+			var step []*fileStep
+			if *explainTarget != "" {
+				step = []*fileStep{{
+					file:    diff.File,
+					unit:    editUnit(diff, hunks[diff.File]),
+					edit:    edit,
+					factors: factors,
+					score:   fileScore,
+				}}
+				steps = append(steps, step[0])
+			}
 
-				// update group entry
-				files = append(files, diff.File)
+			// update group entry
+			files = append(files, diff.File)
+			if *groupAgg == "max" {
+				score = math.Max(score, fileScore)
+			} else {
 				score += fileScore
-
-				// update file entry
-				add(diff.File, commit, fileScore)
 			}
+
+			// update file entry
+			add([]string{diff.File}, commit, fileScore, step)
 		}
 
 		if len(files) >= 2 {
-			score *= float64(len(files))
+			if *groupAgg == "sum" {
+				score *= float64(len(files))
+			}
 			// per-group
-			group := strings.Join(files, ",")
-			add(group, commit, score)
+			add(files, commit, score, steps)
+		}
+	}
+
+	var contains map[string]bool
+	if *containsPattern != "" {
+		contains, err = GitGrep(ctx, *containsPattern)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var baselineFiles map[string]bool
+	if *baseline != "" {
+		baselineFiles, err = GitLsTree(ctx, *baseline)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var recentFiles map[string]bool
+	if *recent > 0 {
+		recentFiles = make(map[string]bool)
+		for _, commit := range mostRecent(commits, *recent) {
+			for _, diff := range commit.Diff {
+				recentFiles[diff.File] = true
+			}
 		}
 	}
 
+	var active map[string]time.Time
+	var recent time.Time
+	if *orphaned {
+		active, recent = lastActive(commits)
+	}
+
 	// so far it calculates based on edit distance
 	var targets []*Target
+	var all []*Target
 	for _, t := range m {
-		// diff analysis
-		plus := make(map[string]string)
-		minus := make(map[string]string)
-		delta := make(map[string]int)
-
-		for _, commit := range t.Commit {
-			add, del, err := GitDiff(commit.ID)
-			if err != nil {
-				continue
-			}
-			for _, line := range add {
-				if id, ok := minus[line]; ok && id != commit.ID {
-					delta[line]++
-					delete(minus, line)
-				}
-				plus[line] = commit.ID
-			}
-			for _, line := range del {
-				if id, ok := plus[line]; ok && id != commit.ID {
-					delta[line]++
-					delete(plus, line)
-				}
-				minus[line] = commit.ID
+		all = append(all, t)
+	}
+	// in a fixed order, so that runs with the same input are identical
+	sort.Sort(ByFiles(all))
+	var pr *progress
+	if *showProgress {
+		pr = newProgress(len(all))
+	}
+	for i, t := range all {
+		pr.update(i + 1)
+		if contains != nil && !t.anyFile(contains) {
+			continue
+		}
+		if baselineFiles != nil && !t.allFiles(baselineFiles) {
+			continue
+		}
+		if recentFiles != nil && !t.allFiles(recentFiles) {
+			continue
+		}
+		t.countEdits()
+		t.countOwners()
+		if *reviewShare > 0 {
+			t.findReview(*reviewShare)
+		}
+		if *orphaned {
+			t.findInactiveOwners(active, recent)
+		}
+		if len(t.Files) == 1 {
+			t.Escaped = escaped[t.Files[0]]
+		}
+		if keywordRegexp != nil {
+			t.findKeywords()
+		}
+		t.editScore = t.Score
+		if !*noReasons {
+			if err := ctx.Err(); err != nil {
+				return nil, err
 			}
+			findReasons(ctx, t, diff)
+			t.Score *= float64(t.delta)
 		}
-		var total int
-		for line, count := range delta {
-			t.Reason = append(t.Reason, &Reason{
-				Line:  line,
-				Count: count,
-			})
-			total += count
+		if *scorer != "" {
+			score, err := ExternalScore(ctx, *scorer, t)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "scorer: %s: %v\n", t.Name, err)
+			} else {
+				t.Score = score
+				t.external = true
+			}
 		}
-		sort.Sort(ByCount(t.Reason))
-		t.Score *= float64(total)
 		if t.Score > 0 {
+			t.Velocity = t.velocity()
 			targets = append(targets, t)
 		}
 	}
 	// sort this list
 	sort.Sort(ByScore(targets))
 
+	if *normalize && len(targets) > 0 {
+		top := targets[0].Score
+		for _, t := range targets {
+			t.RawScore = t.Score
+			t.Score = t.Score / top * 100
+		}
+	}
+	if *sortBy == "velocity" {
+		sort.Sort(ByVelocity(targets))
+	}
+
+	drift.Files = len(driftFiles)
+	if churn > 0 {
+		drift.Share = float64(drift.Lines) / float64(churn)
+	}
+	return &Analysis{
+		Targets: targets,
+		All:     all,
+		Commits: commits,
+		Drift:   drift,
+	}, nil
+}
+
+func printText(r *Report) {
+	var hidden int
+	sizeScoreColumn(r.Targets)
 	// top K
-	for i, t := range targets {
-		if i == *topTarget {
-			break
+	for _, t := range r.Targets {
+		if t.Score < *minScoreDisplay {
+			hidden++
+			continue
+		}
+		fmt.Println(formatRow(t))
+		if t.Escaped != "" {
+			fmt.Printf("         moved out of %s from %s\n", *scope, t.Escaped)
+		}
+		if len(t.InactiveOwners) > 0 {
+			fmt.Printf("         owner inactive: %s\n", strings.Join(t.InactiveOwners, ", "))
+		}
+		if t.TestsStale {
+			fmt.Printf("         tests rarely updated: %d of %d commits\n", t.TestCommits, len(t.Commit))
 		}
-		fmt.Printf("%8.1f %-40s %4d\n",
-			t.Score,
-			shorten(t.Name, 40),
-			len(t.Commit),
-		)
 		for i, reason := range t.Reason {
 			if i == *topReason {
 				break
 			}
 			if *detail || reason.Count > 1 {
-				fmt.Printf("    %4d %s\n", reason.Count, reason.Line)
+				fmt.Printf("    %4d %s\n", reason.Count, paint(colorMuted, reason.Line))
 			}
 		}
 		if *detail {
+			fmt.Printf("         instability %.2f\n", t.Instability)
+			fmt.Printf("         velocity %.*f per day\n", *precision, t.Velocity)
+			fmt.Printf("         skew %+.2f (+%d -%d)\n", t.Skew, t.Add, t.Delete)
+			if t.HotFile != "" {
+				fmt.Printf("         hot range %s:%d-%d (%.0f%% of changed lines)\n",
+					t.HotFile, t.HotStart, t.HotEnd, t.Concentration*100)
+				fmt.Printf("         entropy %.2f bits\n", t.Entropy)
+			}
+			var owners []string
+			for _, o := range t.Owners {
+				owners = append(owners, fmt.Sprintf("%s %d", o.Name, o.Count))
+			}
+			fmt.Printf("         owners %s", strings.Join(owners, ", "))
+			if t.NewOwners > 0 {
+				fmt.Printf(" (%d new in the last quarter)", t.NewOwners)
+			}
+			fmt.Println()
+			if t.Contested > 0 {
+				fmt.Printf("         contested lines %d\n", t.Contested)
+			}
+			if len(t.Keywords) > 0 {
+				fmt.Printf("         keywords %s\n", strings.Join(t.Keywords, ", "))
+			}
+			if t.BlameOwner != "" {
+				fmt.Printf("         blame %s (%.0f%% of lines)\n", t.BlameOwner, t.BlameShare*100)
+			}
+			printReview(t)
+			for _, cmd := range drillDown(t) {
+				fmt.Printf("         $ %s\n", paint(colorDim, cmd))
+			}
 			for _, commit := range t.Commit {
-				var msg string
-				if len(commit.Message) > 0 {
-					msg = commit.Message[0]
+				msg := commit.Subject
+				if commit.Type != "" {
+					prefix := commit.Type
+					if commit.Scope != "" {
+						prefix += "(" + commit.Scope + ")"
+					}
+					msg = paint(colorMuted, prefix+":") + " " + msg
 				}
-				fmt.Printf("         %s %s (%s)\n",
-					commit.ID[:7],
+				fmt.Printf("         %s %s %s (%s)\n",
+					paint(colorDim, shortID(commit.ID)),
+					formatTime(commit.Author.Time),
 					msg,
 					commit.Author.Name,
 				)
+				for i := len(commit.Squashed) - 1; i >= 0; i-- {
+					c := commit.Squashed[i]
+					fmt.Printf("           %s %s %s\n",
+						paint(colorDim, shortID(c.ID)),
+						formatTime(c.Author.Time),
+						c.Subject,
+					)
+				}
 			}
 		}
 		fmt.Println()
 	}
-	fmt.Printf("total targets: %d, total commits: %d\n", len(targets), len(commits))
+	printQuickWins(r.QuickWins)
+	printBudget(r.Budget)
+	printFixups(r.Fixups)
+	printSystemicReasons(r.Systemic)
+	printGlobalReasons(r.Global)
+	printCoupling(r.Coupling)
+	printRegressions(r.Regressions)
+	printByHour(r.ByHour)
+	printByWeekday(r.ByWeekday)
+	printConfigDrift(r.Drift)
+	printStats(r.Stats)
+	fmt.Printf("total targets: %d, total commits: %d", r.TotalTargets, r.TotalCommits)
+	if hidden > 0 {
+		fmt.Printf(", hidden targets: %d", hidden)
+	}
+	fmt.Println()
 }
 
+func shortID(id string) string {
+	if len(id) > 7 {
+		return id[:7]
+	}
+	return id
+}
+
+// shorten truncates s to l terminal columns.
 func shorten(s string, l int) string {
 	if l < 3 {
 		return ""
 	}
-	if len(s) > l {
-		return s[:l-3] + "..."
+	if displayWidth(s) <= l {
+		return s
+	}
+	var w int
+	for i, r := range s {
+		w += runeWidth(r)
+		if w > l-3 {
+			return s[:i] + "..."
+		}
 	}
 	return s
 }
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// git runs git in dir with a fixed identity and no user config.
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_CONFIG_GLOBAL=/dev/null",
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_AUTHOR_NAME=u", "GIT_AUTHOR_EMAIL=u@x",
+		"GIT_COMMITTER_NAME=u", "GIT_COMMITTER_EMAIL=u@x",
+	)
+	b, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("git %s: %v", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func TestGitDiff(t *testing.T) {
+	dir := t.TempDir()
+	write := func(file, s string) {
+		f, err := os.OpenFile(dir+"/"+file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		f.WriteString(s)
+	}
+	git(t, dir, "init", "-q", "-b", "main")
+	write("a.go", "a := f(1)\nb := f(2)\n")
+	git(t, dir, "add", "a.go")
+	git(t, dir, "commit", "-qm", "root")
+	root := git(t, dir, "rev-parse", "HEAD")
+	git(t, dir, "checkout", "-qb", "side")
+	write("b.go", "c := g(3)\n")
+	git(t, dir, "add", "b.go")
+	git(t, dir, "commit", "-qm", "side")
+	side := git(t, dir, "rev-parse", "HEAD")
+	git(t, dir, "checkout", "-q", "main")
+	write("a.go", "d := h(4)\n")
+	git(t, dir, "commit", "-qam", "main")
+	tip := git(t, dir, "rev-parse", "HEAD")
+	git(t, dir, "merge", "-q", "--no-edit", "side")
+	merge := git(t, dir, "rev-parse", "HEAD")
+	emptyTree := git(t, dir, "hash-object", "-t", "tree", "/dev/null")
+
+	t.Chdir(dir)
+	diff := NewGitDiff(keepAll)
+	for _, tt := range []struct {
+		name string
+		id   string
+		old  []string
+	}{
+		{"commit", tip, []string{"diff", tip + "^!"}},
+		{"branch commit", side, []string{"diff", side + "^!"}},
+		// git diff <id>^! fails without a parent
+		{"root", root, []string{"diff", emptyTree, root}},
+		// git diff <id>^! is a combined diff, which is empty for a clean merge
+		{"merge", merge, []string{"diff", merge + "^1", merge}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := diff(context.Background(), tt.id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := ParseDiff([]byte(git(t, dir, tt.old...)+"\n"), keepAll)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("git show: %+v\ngit %s: %+v", got, strings.Join(tt.old, " "), want)
+			}
+			if len(got.Add) == 0 {
+				t.Errorf("no added lines")
+			}
+		})
+	}
+}
+
+func TestParseDiff(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		diff     string
+		add, del []DiffLine
+		hunks    []Hunk
+	}{
+		{
+			name: "change",
+			diff: `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1,2 +1,2 @@
+ x := 1
+-y := f(x)
++y := g(x)
+`,
+			add:   []DiffLine{{"a.go", "y := g(x)"}},
+			del:   []DiffLine{{"a.go", "y := f(x)"}},
+			hunks: []Hunk{{"a.go", 1, 2}},
+		},
+		{
+			name: "decrement and increment",
+			diff: `diff --git a/a.c b/a.c
+--- a/a.c
++++ b/a.c
+@@ -1,2 +1,2 @@
+---count;
++++i;
+ return;
+`,
+			add:   []DiffLine{{"a.c", "++i;"}},
+			del:   []DiffLine{{"a.c", "--count;"}},
+			hunks: []Hunk{{"a.c", 1, 2}},
+		},
+		{
+			name: "body lines that look like headers",
+			diff: `diff --git a/q.sql b/q.sql
+--- a/q.sql
++++ b/q.sql
+@@ -1 +1 @@
+--- a/old.sql
++++ b/new.sql
+diff --git a/r.sql b/r.sql
+--- a/r.sql
++++ b/r.sql
+@@ -3,0 +4 @@
++x = 1
+`,
+			add: []DiffLine{{"q.sql", "++ b/new.sql"}, {"r.sql", "x = 1"}},
+			del: []DiffLine{{"q.sql", "-- a/old.sql"}},
+			hunks: []Hunk{
+				{"q.sql", 1, 1},
+				{"r.sql", 4, 1},
+			},
+		},
+		{
+			name: "no newline at end of file",
+			diff: `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1 +1 @@
+-x := 1
+\ No newline at end of file
++x := 2
+\ No newline at end of file
+`,
+			add:   []DiffLine{{"a.go", "x := 2"}},
+			del:   []DiffLine{{"a.go", "x := 1"}},
+			hunks: []Hunk{{"a.go", 1, 1}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			p := ParseDiff([]byte(tt.diff), keepAll)
+			if !reflect.DeepEqual(p.Add, tt.add) {
+				t.Errorf("add = %q, want %q", p.Add, tt.add)
+			}
+			if !reflect.DeepEqual(p.Del, tt.del) {
+				t.Errorf("del = %q, want %q", p.Del, tt.del)
+			}
+			if !reflect.DeepEqual(p.Hunks, tt.hunks) {
+				t.Errorf("hunks = %v, want %v", p.Hunks, tt.hunks)
+			}
+		})
+	}
+}
+
+// logRecord is a commit as printed by git log with logFormat and --numstat.
+func logRecord(id, name, email, at, body, numstat string) string {
+	return "\x1e" + strings.Join([]string{id, "tree", "", name, email, at, body, "\n" + numstat}, "\x1f")
+}
+
+func TestParseLog(t *testing.T) {
+	sha := strings.Repeat("a", 40)
+	for _, tt := range []struct {
+		name    string
+		log     string
+		message [][]string
+		errs    int
+	}{
+		{
+			name: "commit line in message",
+			log: logRecord("1", "u", "u@x", "1", "Revert \"x\"\n\nThis reverts\ncommit "+sha+".\n", "1\t1\ta.go\n") +
+				logRecord("2", "u", "u@x", "2", "y\n", "2\t0\tb.go\n"),
+			message: [][]string{{`Revert "x"`, "This reverts", "commit " + sha + "."}, {"y"}},
+		},
+		{
+			name:    "empty body",
+			log:     logRecord("1", "u", "u@x", "1", "", "1\t1\ta.go\n"),
+			message: [][]string{nil},
+		},
+		{
+			name:    "paragraphs",
+			log:     logRecord("1", "u", "u@x", "1", "subject\n\nfirst\nparagraph\n\nsecond\n", "1\t1\ta.go\n"),
+			message: [][]string{{"subject", "first", "paragraph", "second"}},
+		},
+		{
+			name:    "numstat-like message",
+			log:     logRecord("1", "u", "u@x", "1", "subject\n\n3\t4\tfake.go\n", "1\t1\ta.go\n"),
+			message: [][]string{{"subject", "3\t4\tfake.go"}},
+		},
+		{
+			name:    "bad author time",
+			log:     logRecord("1", "u", "u@x", "", "subject\n", "1\t1\ta.go\n"),
+			message: [][]string{{"subject"}},
+			errs:    1,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			commits, errs := ParseLog([]byte(tt.log))
+			if len(errs) != tt.errs {
+				t.Errorf("errs = %v, want %d", errs, tt.errs)
+			}
+			if len(commits) != len(tt.message) {
+				t.Fatalf("%d commits, want %d", len(commits), len(tt.message))
+			}
+			for i, commit := range commits {
+				if !reflect.DeepEqual(commit.Message, tt.message[i]) {
+					t.Errorf("commit %d: message = %q, want %q", i, commit.Message, tt.message[i])
+				}
+				if len(commit.Diff) != 1 {
+					t.Errorf("commit %d: diff = %v, want 1 file", i, commit.Diff)
+				}
+			}
+		})
+	}
+}
+
+// authorIDs returns the ID and author name of commits.
+func authorIDs(commits []*Commit) (ids []string) {
+	for _, commit := range commits {
+		ids = append(ids, commit.ID+" "+commit.Author.Name)
+	}
+	return
+}
+
+// testMissingAuthor runs fixAuthors on commits 1 to 4, parsed with an empty
+// name, no email, no time and a valid author, in both -missing-author modes.
+func testMissingAuthor(t *testing.T, commits []*Commit) {
+	t.Helper()
+	defer func(v string) { *missingAuthor = v }(*missingAuthor)
+	for _, tt := range []struct {
+		mode string
+		want []string
+	}{
+		{"skip", []string{"2 u", "4 u"}},
+		{"unknown", []string{"1 unknown", "2 u", "4 u"}},
+	} {
+		*missingAuthor = tt.mode
+		var in []*Commit
+		for _, commit := range commits {
+			c := *commit
+			in = append(in, &c)
+		}
+		if got := authorIDs(fixAuthors(in)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("-missing-author=%s: %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestParseLogMissingAuthor(t *testing.T) {
+	commits, _ := ParseLog([]byte(
+		logRecord("1", "", "u@x", "1", "x\n", "1\t0\ta.go\n") +
+			logRecord("2", "u", "", "2", "x\n", "1\t0\ta.go\n") +
+			logRecord("3", "u", "u@x", "", "x\n", "1\t0\ta.go\n") +
+			logRecord("4", "u", "u@x", "4", "x\n", "1\t0\ta.go\n")))
+	if len(commits) != 4 {
+		t.Fatalf("%d commits, want 4", len(commits))
+	}
+	if commits[1].Author.Email != "" || commits[1].Author.Time != time.Unix(2, 0) {
+		t.Errorf("author without email = %+v", commits[1].Author)
+	}
+	testMissingAuthor(t, commits)
+}
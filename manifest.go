@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// manifestEnv are the environment variables that change what a run does.
+var manifestEnv = []string{"GIT_DIR", "GIT_WORK_TREE", "GIT_CONFIG_GLOBAL", "NO_COLOR", "TZ"}
+
+// Manifest records how a report was generated, so that it can be reproduced.
+type Manifest struct {
+	Version string            `json:"version"`
+	Go      string            `json:"go"`
+	Host    string            `json:"host"`
+	Time    time.Time         `json:"time"`
+	Head    string            `json:"head,omitempty"`
+	After   string            `json:"after"`
+	Before  string            `json:"before"`
+	Flags   map[string]string `json:"flags"`
+	Env     map[string]string `json:"env,omitempty"`
+}
+
+// toolVersion is the module version, or the VCS revision of a local build.
+func toolVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" {
+			return s.Value
+		}
+	}
+	return "devel"
+}
+
+// newManifest describes this run.
+func newManifest(ctx context.Context) *Manifest {
+	m := &Manifest{
+		Version: toolVersion(),
+		Go:      runtime.Version(),
+		Time:    time.Now().In(location),
+		After:   *after,
+		Before:  *before,
+		Flags:   make(map[string]string),
+	}
+	m.Host, _ = os.Hostname()
+	if *mergeBase != "" {
+		m.After, m.Before = *mergeBase, "HEAD"
+	}
+	if *fromTag != "" {
+		m.After, m.Before = *fromTag, *toTag
+		if *toTag == "" {
+			m.Before = "HEAD"
+		}
+	}
+	if b, err := exec.CommandContext(ctx, "git", "rev-parse", "HEAD").Output(); err == nil {
+		m.Head = strings.TrimSpace(string(b))
+	}
+	flag.VisitAll(func(f *flag.Flag) {
+		m.Flags[f.Name] = f.Value.String()
+	})
+	for _, key := range manifestEnv {
+		if v, ok := os.LookupEnv(key); ok {
+			if m.Env == nil {
+				m.Env = make(map[string]string)
+			}
+			m.Env[key] = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// mdCode formats s as a Markdown code span that is safe in a table cell.
+func mdCode(s string) string {
+	s = strings.Replace(s, "|", `\|`, -1)
+	if strings.Contains(s, "`") {
+		return "`` " + s + " ``"
+	}
+	return "`" + s + "`"
+}
+
+// writeMarkdown writes the targets of r as a GitHub-flavored Markdown table.
+func writeMarkdown(w io.Writer, r *Report) error {
+	fmt.Fprintln(w, "| # | target | score | commits | top reason |")
+	fmt.Fprintln(w, "|--:|--------|------:|--------:|------------|")
+	for i, t := range r.Targets {
+		var files []string
+		for _, file := range t.Files {
+			files = append(files, mdCode(file))
+		}
+		var reason string
+		if len(t.Reason) > 0 {
+			reason = fmt.Sprintf("%s (%d)", mdCode(t.Reason[0].Line), t.Reason[0].Count)
+		}
+		fmt.Fprintf(w, "| %d | %s | %.*f | %d | %s |\n",
+			i+1, strings.Join(files, ", "), *precision, t.Score, len(t.Commit), reason)
+	}
+	_, err := fmt.Fprintf(w, "\ntotal targets: %d, total commits: %d\n", r.TotalTargets, r.TotalCommits)
+	return err
+}
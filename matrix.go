@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// WriteMatrix writes which commits changed which files as CSV, one row per
+// commit and file, before any scoring.
+func WriteMatrix(ctx context.Context, w io.Writer) error {
+	commits, _, err := loadCommits(ctx)
+	if err != nil {
+		return err
+	}
+	exts := fileExts()
+	out := csv.NewWriter(w)
+	out.Write([]string{"commit", "time", "author", "file", "add", "delete"})
+	for _, commit := range commits {
+		for _, diff := range commit.Diff {
+			if !keepFile(diff.File) || !hasExt(diff.File, exts) || !inScope(diff.File) {
+				continue
+			}
+			out.Write([]string{
+				commit.ID,
+				commit.Author.Time.In(location).Format(time.RFC3339),
+				commit.Author.Name,
+				diff.File,
+				strconv.Itoa(diff.Add),
+				strconv.Itoa(diff.Delete),
+			})
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MergeReports combines JSON reports of separate runs into one ranking. With
+// mode "sum", targets of the same name are added up; with "prefix", each
+// name is prefixed by the base name of its report so that repos stay apart.
+func MergeReports(paths []string, mode string) (*Report, error) {
+	merged := new(Report)
+	byName := make(map[string]*Target)
+	for _, path := range paths {
+		r, err := readReport(path)
+		if err != nil {
+			return nil, err
+		}
+		merged.TotalCommits += r.TotalCommits
+		repo := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		for _, t := range r.Targets {
+			if mode == "prefix" {
+				for i, file := range t.Files {
+					t.Files[i] = repo + "/" + file
+				}
+				t.Name = strings.Join(t.Files, *groupSeparator)
+			}
+			prev, ok := byName[t.Name]
+			if !ok {
+				byName[t.Name] = t
+				merged.Targets = append(merged.Targets, t)
+				continue
+			}
+			prev.mergeTarget(t)
+		}
+	}
+	sort.Sort(ByScore(merged.Targets))
+	merged.TotalTargets = len(merged.Targets)
+	merged.Stats = computeStats(merged.Targets)
+	if len(merged.Targets) > *topTarget {
+		merged.Targets = merged.Targets[:*topTarget]
+	}
+	return merged, nil
+}
+
+// mergeTarget adds the score, commits and reasons of o to t.
+func (t *Target) mergeTarget(o *Target) {
+	t.Score += o.Score
+	t.RawScore += o.RawScore
+	t.Commit = append(t.Commit, o.Commit...)
+	t.countEdits()
+	t.countOwners()
+
+	count := make(map[string]*Reason)
+	for _, r := range t.Reason {
+		count[r.Line] = r
+	}
+	for _, r := range o.Reason {
+		if prev, ok := count[r.Line]; ok {
+			prev.Count += r.Count
+		} else {
+			t.Reason = append(t.Reason, r)
+		}
+	}
+	sort.Stable(ByCount(t.Reason))
+}
+
+func parseMergeMode(mode string) error {
+	switch mode {
+	case "prefix", "sum":
+		return nil
+	}
+	return fmt.Errorf("invalid -merge-mode %q: must be prefix or sum", mode)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rawAuthorRegexp also matches authors without <email>, so that their time
+// is kept.
+var rawAuthorRegexp = regexp.MustCompile(`^author (.*?) ?(?:<(.*)> )?([0-9]+) [+-][0-9]{4}$`)
+
+// ReadLogFile reads commits from a saved git log --format=raw --numstat.
+func ReadLogFile(path string) ([]*Commit, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	commits, errs := ParseRawLog(b)
+	if *strict && len(errs) > 0 {
+		for _, e := range errs {
+			fmt.Fprintln(os.Stderr, e)
+		}
+		return nil, fmt.Errorf("%s: %d lines cannot be parsed", path, len(errs))
+	}
+	if *excludeAuthor != "" {
+		commits = excludeAuthors(commits, strings.Split(*excludeAuthor, ","))
+	}
+	return commits, nil
+}
+
+// ParseRawLog parses the output of git log --format=raw --numstat. Parts that
+// cannot be parsed are skipped and returned as errs.
+func ParseRawLog(b []byte) (commits []*Commit, errs []*ParseError) {
+	var commit *Commit
+	// headers end at the first blank line
+	var header bool
+	// lines of a commit without ID are skipped with it
+	var skip bool
+	s := bufio.NewScanner(bytes.NewReader(b))
+	s.Buffer(nil, len(b)+1)
+	for s.Scan() {
+		line := s.Text()
+		if strings.HasPrefix(line, "commit ") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				errs = append(errs, &ParseError{"commit without id", line})
+				commit, skip = nil, true
+				continue
+			}
+			commit, skip = &Commit{ID: fields[1]}, false
+			commits = append(commits, commit)
+			header = true
+			continue
+		}
+		if commit == nil {
+			if !skip && strings.TrimSpace(line) != "" {
+				errs = append(errs, &ParseError{"line before first commit", line})
+			}
+			continue
+		}
+		switch {
+		case header && line == "":
+			header = false
+		case header && strings.HasPrefix(line, "tree "):
+			commit.Tree = strings.TrimPrefix(line, "tree ")
+		case header && strings.HasPrefix(line, "parent "):
+			commit.Parent = strings.TrimPrefix(line, "parent ")
+			commit.Parents = append(commit.Parents, commit.Parent)
+		case header && strings.HasPrefix(line, "author "):
+			match := rawAuthorRegexp.FindStringSubmatch(line)
+			if match == nil {
+				errs = append(errs, &ParseError{"bad author", line})
+				continue
+			}
+			i, _ := strconv.ParseInt(match[3], 10, 64)
+			commit.Author = Author{Name: match[1], Email: match[2], Time: time.Unix(i, 0)}
+		case header:
+			// committer, signatures and other headers
+		case strings.HasPrefix(line, "    "):
+			if msg := strings.TrimPrefix(line, "    "); strings.TrimSpace(msg) != "" {
+				commit.Message = append(commit.Message, msg)
+			}
+		default:
+			if err := commit.addNumstat(line); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return
+}
+
+// DirDiff is GitDiff for commits whose diffs are saved in -diff-dir, as
+// <commit>.diff files from git show --format= <commit>.
+func DirDiff(ctx context.Context, commitID string) (*Patch, error) {
+	if p, ok := diffCache[commitID]; ok {
+		return p, nil
+	}
+	b, err := ioutil.ReadFile(filepath.Join(*diffDir, commitID+".diff"))
+	if err != nil {
+		return nil, err
+	}
+	p := ParseDiff(b, UsefulLine)
+	diffCache[commitID] = p
+	return p, nil
+}
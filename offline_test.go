@@ -0,0 +1,69 @@
+package main
+
+import "testing"
+
+func TestParseRawLogMissingAuthor(t *testing.T) {
+	commits, errs := ParseRawLog([]byte(`commit 1
+tree t
+author <u@x> 1 +0000
+
+    x
+
+1	0	a.go
+commit 2
+tree t
+author u 2 +0000
+
+    x
+
+1	0	a.go
+commit 3
+tree t
+author u <u@x>
+
+    x
+
+1	0	a.go
+commit 4
+tree t
+author u <u@x> 4 +0000
+
+    x
+
+1	0	a.go
+`))
+	if len(errs) != 1 {
+		t.Errorf("errs = %v, want the author without time", errs)
+	}
+	if len(commits) != 4 {
+		t.Fatalf("%d commits, want 4", len(commits))
+	}
+	if a := commits[1].Author; a.Name != "u" || a.Email != "" || a.Time.Unix() != 2 {
+		t.Errorf("author without email = %+v", a)
+	}
+	testMissingAuthor(t, commits)
+}
+
+func TestParseRawLogCommitWithoutID(t *testing.T) {
+	commits, errs := ParseRawLog([]byte(`commit 
+tree t
+author u <u@x> 1 +0000
+
+    x
+
+1	0	a.go
+commit 2
+tree t
+author u <u@x> 2 +0000
+
+    y
+
+1	0	b.go
+`))
+	if len(errs) != 1 || errs[0].Kind != "commit without id" {
+		t.Errorf("errs = %v, want the commit without id", errs)
+	}
+	if len(commits) != 1 || commits[0].ID != "2" || len(commits[0].Diff) != 1 {
+		t.Errorf("commits = %+v, want commit 2 only", commits)
+	}
+}
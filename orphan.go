@@ -0,0 +1,43 @@
+package main
+
+import "time"
+
+// lastActive returns when each author last committed, and the start of the
+// last quarter of the history.
+func lastActive(commits []*Commit) (active map[string]time.Time, recent time.Time) {
+	active = make(map[string]time.Time)
+	var first, last time.Time
+	for _, commit := range commits {
+		at := commit.Author.Time
+		if at.After(active[commit.Author.Name]) {
+			active[commit.Author.Name] = at
+		}
+		if first.IsZero() || at.Before(first) {
+			first = at
+		}
+		if at.After(last) {
+			last = at
+		}
+	}
+	recent = last.Add(-last.Sub(first) / 4)
+	return
+}
+
+// findInactiveOwners sets InactiveOwners to the main authors of t, who made
+// at least half of its commits, if none of them committed anywhere since
+// recent.
+func (t *Target) findInactiveOwners(active map[string]time.Time, recent time.Time) {
+	t.InactiveOwners = nil
+	var main []string
+	var n int
+	for _, o := range t.Owners {
+		if active[o.Name].After(recent) {
+			return
+		}
+		main = append(main, o.Name)
+		if n += o.Count; 2*n >= len(t.Commit) {
+			break
+		}
+	}
+	t.InactiveOwners = main
+}
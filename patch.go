@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	mboxRegexp    = regexp.MustCompile(`^From ([0-9a-f]{40}) `)
+	subjectRegexp = regexp.MustCompile(`^(?:\[[^]]*\] *)+`)
+	gitDiffRegexp = regexp.MustCompile(`^diff --git `)
+)
+
+var patchDiffs = make(map[string]*Patch)
+
+// PatchDiff is GitDiff for commits read by ReadPatches.
+func PatchDiff(ctx context.Context, commitID string) (*Patch, error) {
+	p, ok := patchDiffs[commitID]
+	if !ok {
+		return nil, fmt.Errorf("unknown patch %s", commitID)
+	}
+	return p, nil
+}
+
+// ReadPatches reads commits from a mbox file, a single patch file or a
+// directory of .patch files.
+func ReadPatches(path string) (commits []*Commit, err error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	files := []string{path}
+	if fi.IsDir() {
+		files, err = filepath.Glob(filepath.Join(path, "*.patch"))
+		if err != nil {
+			return
+		}
+		sort.Strings(files)
+	}
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for i, msg := range splitMbox(b) {
+			commit, p := parsePatch(msg)
+			if commit.ID == "" {
+				commit.ID = fmt.Sprintf("%s#%d", filepath.Base(file), i+1)
+			}
+			patchDiffs[commit.ID] = p
+			commits = append(commits, commit)
+		}
+	}
+	return
+}
+
+// splitMbox splits b at each "From <sha>" line that starts a message.
+func splitMbox(b []byte) (msgs [][]byte) {
+	var start int
+	var offset int
+	s := bufio.NewScanner(bytes.NewReader(b))
+	s.Buffer(nil, len(b)+1)
+	for s.Scan() {
+		if mboxRegexp.Match(s.Bytes()) && offset > start {
+			msgs = append(msgs, b[start:offset])
+			start = offset
+		}
+		offset += len(s.Bytes()) + 1
+	}
+	if start < len(b) {
+		msgs = append(msgs, b[start:])
+	}
+	return
+}
+
+// parsePatch returns the commit of a patch and its diff. The commit has no ID
+// unless the patch starts with "From <sha>".
+func parsePatch(b []byte) (*Commit, *Patch) {
+	commit := new(Commit)
+	if match := mboxRegexp.FindSubmatch(b); match != nil {
+		commit.ID = string(match[1])
+		b = b[bytes.IndexByte(b, '\n')+1:]
+	}
+	body := b
+	if msg, err := mail.ReadMessage(bytes.NewReader(b)); err == nil && msg.Header.Get("From") != "" {
+		if addr, err := mail.ParseAddress(msg.Header.Get("From")); err == nil {
+			commit.Author.Name = addr.Name
+			commit.Author.Email = addr.Address
+		}
+		if t, err := msg.Header.Date(); err == nil {
+			commit.Author.Time = t
+		}
+		subject := msg.Header.Get("Subject")
+		if s, err := new(mime.WordDecoder).DecodeHeader(subject); err == nil {
+			subject = s
+		}
+		commit.Message = append(commit.Message, subjectRegexp.ReplaceAllString(subject, ""))
+		body, _ = ioutil.ReadAll(msg.Body)
+	}
+
+	// the diff starts at the first file header; the message ends at "---"
+	var diff []byte
+	var stat bool
+	lines := strings.Split(string(body), "\n")
+	for i, line := range lines {
+		if gitDiffRegexp.MatchString(line) ||
+			strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") {
+			diff = []byte(strings.Join(lines[i:], "\n"))
+			break
+		}
+		if line == "---" {
+			stat = true
+		}
+		if !stat && len(commit.Message) > 0 {
+			commit.Message = append(commit.Message, strings.TrimSpace(line))
+		}
+	}
+	commit.Message = trimMessage(commit.Message)
+	commit.Diff = numstat(diff)
+	return commit, ParseDiff(diff, nil)
+}
+
+func trimMessage(msg []string) []string {
+	for len(msg) > 0 && msg[len(msg)-1] == "" {
+		msg = msg[:len(msg)-1]
+	}
+	return msg
+}
+
+// numstat derives per-file added and deleted line counts from hunks, like
+// git log --numstat.
+func numstat(b []byte) (diffs []Diff) {
+	var oldFile string
+	var oldLeft, newLeft int
+	s := bufio.NewScanner(bytes.NewReader(b))
+	s.Buffer(nil, len(b)+1)
+	for s.Scan() {
+		line := s.Text()
+		if oldLeft > 0 || newLeft > 0 {
+			cur := &diffs[len(diffs)-1]
+			switch {
+			case strings.HasPrefix(line, "+"):
+				cur.Add++
+				newLeft--
+			case strings.HasPrefix(line, "-"):
+				cur.Delete++
+				oldLeft--
+			case strings.HasPrefix(line, `\`):
+			default:
+				oldLeft--
+				newLeft--
+			}
+			continue
+		}
+		if match := oldFileRegexp.FindStringSubmatch(line); match != nil {
+			oldFile = match[1]
+		} else if match := newFileRegexp.FindStringSubmatch(line); match != nil {
+			diffs = append(diffs, Diff{File: match[1]})
+		} else if line == "+++ /dev/null" {
+			diffs = append(diffs, Diff{File: oldFile})
+		} else if match := hunkRegexp.FindStringSubmatch(line); match != nil && len(diffs) > 0 {
+			oldLeft, newLeft = hunkLen(match[1]), hunkLen(match[3])
+		}
+	}
+	return
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writePatches writes patches to a temporary directory as 1.patch, 2.patch
+// and so on, and returns the directory.
+func writePatches(t *testing.T, patches ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for i, p := range patches {
+		name := filepath.Join(dir, string(rune('1'+i))+".patch")
+		if err := os.WriteFile(name, []byte(p), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestReadPatchesWithoutSHA(t *testing.T) {
+	dir := writePatches(t, `From: A U Thor <a@x>
+Date: Mon, 2 Jan 2023 15:04:05 +0000
+Subject: [PATCH] change a
+
+---
+diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1 +1 @@
+-x := f(1)
++x := f(2)
+`, `From: A U Thor <a@x>
+Date: Tue, 3 Jan 2023 15:04:05 +0000
+Subject: [PATCH] change b
+
+---
+diff --git a/b.go b/b.go
+--- a/b.go
++++ b/b.go
+@@ -1 +1 @@
+-y := g(1)
++y := g(2)
+`)
+	commits, err := ReadPatches(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ id, file, add string }{
+		{"1.patch#1", "a.go", "x := f(2)"},
+		{"2.patch#1", "b.go", "y := g(2)"},
+	}
+	if len(commits) != len(want) {
+		t.Fatalf("%d commits, want %d", len(commits), len(want))
+	}
+	for i, commit := range commits {
+		if commit.ID != want[i].id {
+			t.Errorf("commit %d: id = %q, want %q", i, commit.ID, want[i].id)
+		}
+		p, err := PatchDiff(context.Background(), commit.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(p.Add) != 1 || p.Add[0].File != want[i].file || p.Add[0].Line != want[i].add {
+			t.Errorf("%s: add = %v, want %s %q", commit.ID, p.Add, want[i].file, want[i].add)
+		}
+	}
+}
+
+func TestAnalyzeHeaderlessPatches(t *testing.T) {
+	dir := writePatches(t, `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1 +1 @@
+-x := f(1)
++x := f(2)
+`, `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1 +1 @@
+-x := f(2)
++x := f(1)
+`)
+	defer func(v string) { *patches = v }(*patches)
+	defer func(v string) { *missingAuthor = v }(*missingAuthor)
+	*patches = dir
+	t.Chdir(t.TempDir())
+	for _, mode := range []string{"skip", "unknown"} {
+		*missingAuthor = mode
+		a, err := Analyze(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(a.Commits) != 2 || len(a.Targets) != 1 || a.Targets[0].Name != "a.go" {
+			t.Errorf("-missing-author=%s: %d commits, targets %v, want a.go of 2 commits",
+				mode, len(a.Commits), a.Targets)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// progress reports how far a loop over targets is on stderr, rewriting one
+// line on a terminal and printing a line per second otherwise.
+type progress struct {
+	n    int
+	tty  bool
+	last time.Time
+}
+
+func newProgress(n int) *progress {
+	return &progress{n: n, tty: isTerminal(os.Stderr)}
+}
+
+func (p *progress) update(i int) {
+	if p == nil {
+		return
+	}
+	interval := time.Second
+	if p.tty {
+		interval = 100 * time.Millisecond
+	}
+	if i < p.n && time.Since(p.last) < interval {
+		return
+	}
+	p.last = time.Now()
+	if p.tty {
+		fmt.Fprintf(os.Stderr, "\ranalyzing target %d/%d", i, p.n)
+		if i == p.n {
+			fmt.Fprintln(os.Stderr)
+		}
+		return
+	}
+	fmt.Fprintf(os.Stderr, "analyzing target %d/%d\n", i, p.n)
+}
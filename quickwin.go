@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// QuickWin is a small file with high churn: an easy refactor with a big
+// stability payoff.
+type QuickWin struct {
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+	Lines int     `json:"lines"`
+	// Ratio is score per line.
+	Ratio float64 `json:"ratio"`
+}
+
+type ByRatio []*QuickWin
+
+func (s ByRatio) Len() int      { return len(s) }
+func (s ByRatio) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s ByRatio) Less(i, j int) bool {
+	if s[i].Ratio != s[j].Ratio {
+		return s[i].Ratio > s[j].Ratio
+	}
+	return s[i].Name < s[j].Name
+}
+
+// findQuickWins returns the top k single-file targets by score per line.
+func findQuickWins(ctx context.Context, targets []*Target, k int) (wins []*QuickWin) {
+	for _, t := range targets {
+		if len(t.Files) != 1 {
+			continue
+		}
+		t.Lines = GitLineCount(ctx, t.Files[0])
+		if t.Lines == 0 {
+			continue
+		}
+		wins = append(wins, &QuickWin{
+			Name:  t.Name,
+			Score: t.Score,
+			Lines: t.Lines,
+			Ratio: t.Score / float64(t.Lines),
+		})
+	}
+	sort.Sort(ByRatio(wins))
+	if len(wins) > k {
+		wins = wins[:k]
+	}
+	return
+}
+
+func printQuickWins(wins []*QuickWin) {
+	if len(wins) == 0 {
+		return
+	}
+	fmt.Println("quick wins:")
+	for _, w := range wins {
+		fmt.Printf("%8.2f %s %6d lines\n", w.Ratio, pad(shorten(w.Name, 40), 40), w.Lines)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+)
+
+type rewriteRule struct {
+	old string
+	new string
+}
+
+// rewriteRules are -rewrite prefix substitutions; the first matching rule
+// wins.
+var rewriteRules []rewriteRule
+
+func parseRewrite(s string) error {
+	rewriteRules = nil
+	for _, rule := range strings.Split(s, ",") {
+		if strings.TrimSpace(rule) == "" {
+			continue
+		}
+		i := strings.IndexByte(rule, '=')
+		if i <= 0 {
+			return fmt.Errorf("invalid -rewrite rule %q: must be old=new", rule)
+		}
+		rewriteRules = append(rewriteRules, rewriteRule{old: rule[:i], new: rule[i+1:]})
+	}
+	return nil
+}
+
+// caseNames maps lower-cased paths to the casing shown, for -fold-case.
+var caseNames map[string]string
+
+// renameFiles rewrites file names of commits so that churn of a file survives
+// moves and case-only renames. With -fold-case, the casing at HEAD is
+// preferred.
+func renameFiles(ctx context.Context, commits []*Commit) {
+	caseNames = nil
+	if *foldCaseFlag {
+		caseNames = make(map[string]string)
+		if files, err := GitLsTree(ctx, "HEAD"); err == nil {
+			for file := range files {
+				caseNames[strings.ToLower(file)] = file
+			}
+		}
+	}
+	if caseNames == nil && len(rewriteRules) == 0 {
+		return
+	}
+	for _, commit := range commits {
+		for i := range commit.Diff {
+			commit.Diff[i].File = canonicalFile(commit.Diff[i].File)
+			if commit.Diff[i].From != "" {
+				commit.Diff[i].From = canonicalFile(commit.Diff[i].From)
+			}
+		}
+	}
+}
+
+// splitRename splits a path of git log --numstat, like "a/{b => c}/d" or
+// "b => c", into the paths before and after a rename. from is empty if the
+// file was not renamed.
+func splitRename(s string) (from, to string) {
+	if i := strings.IndexByte(s, '{'); i >= 0 {
+		if j := strings.IndexByte(s[i:], '}'); j >= 0 {
+			inner := s[i+1 : i+j]
+			if k := strings.Index(inner, " => "); k >= 0 {
+				pre, post := s[:i], s[i+j+1:]
+				from = strings.Replace(pre+inner[:k]+post, "//", "/", 1)
+				to = strings.Replace(pre+inner[k+4:]+post, "//", "/", 1)
+				return
+			}
+		}
+	}
+	if k := strings.Index(s, " => "); k >= 0 {
+		return s[:k], s[k+4:]
+	}
+	return "", s
+}
+
+// escaped maps files that were renamed out of -path to their path in it.
+var escaped map[string]string
+
+// findEscaped follows renames out of -path, so that churn of files moved
+// elsewhere is not lost.
+func findEscaped(commits []*Commit) {
+	escaped = make(map[string]string)
+	if *scope == "" {
+		return
+	}
+	// git log lists the newest first
+	for i := len(commits) - 1; i >= 0; i-- {
+		for _, diff := range commits[i].Diff {
+			if diff.From == "" || inScope(diff.File) {
+				continue
+			}
+			if old, ok := escaped[diff.From]; ok {
+				escaped[diff.File] = old
+			} else if inScope(diff.From) {
+				escaped[diff.File] = diff.From
+			}
+		}
+	}
+}
+
+// inScope reports whether file is in -path, or was renamed out of it.
+func inScope(file string) bool {
+	if *scope == "" || escaped[file] != "" {
+		return true
+	}
+	dir := strings.TrimSuffix(*scope, "/")
+	return file == dir || strings.HasPrefix(file, dir+"/")
+}
+
+// packageOf returns the directory of file with -granularity=package, or file
+// itself.
+func packageOf(file string) string {
+	if *granularity != "package" {
+		return file
+	}
+	return path.Dir(file)
+}
+
+// groupByPackage merges diffs of files in the same directory.
+func groupByPackage(diffs []Diff) (grouped []Diff) {
+	index := make(map[string]int)
+	for _, diff := range diffs {
+		dir := path.Dir(diff.File)
+		if i, ok := index[dir]; ok {
+			grouped[i].Add += diff.Add
+			grouped[i].Delete += diff.Delete
+			continue
+		}
+		index[dir] = len(grouped)
+		grouped = append(grouped, Diff{File: dir, Add: diff.Add, Delete: diff.Delete})
+	}
+	return
+}
+
+// canonicalFile returns the name under which churn of file is aggregated.
+func canonicalFile(file string) string {
+	for _, rule := range rewriteRules {
+		if strings.HasPrefix(file, rule.old) {
+			file = rule.new + file[len(rule.old):]
+			break
+		}
+	}
+	if caseNames == nil {
+		return file
+	}
+	key := strings.ToLower(file)
+	if name, ok := caseNames[key]; ok {
+		return name
+	}
+	caseNames[key] = file
+	return file
+}
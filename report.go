@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Report is the result of a run. It is the schema of -format=json.
+type Report struct {
+	Targets      []*Target    `json:"targets"`
+	TotalTargets int          `json:"total_targets"`
+	TotalCommits int          `json:"total_commits"`
+	Stats        *Stats       `json:"stats,omitempty"`
+	Drift        *ConfigDrift `json:"config_drift,omitempty"`
+
+	QuickWins []*QuickWin       `json:"quick_wins,omitempty"`
+	Budget    *Budget           `json:"budget,omitempty"`
+	Fixups    []*Fixup          `json:"post_merge_fixups,omitempty"`
+	ByHour    []*HourBucket     `json:"by_hour,omitempty"`
+	ByWeekday []*WeekdayBucket  `json:"by_weekday,omitempty"`
+	Systemic  []*SystemicReason `json:"systemic_reasons,omitempty"`
+	Global    []*GlobalReason   `json:"global_reasons,omitempty"`
+	Coupling  []*Coupling       `json:"coupling,omitempty"`
+
+	Regressions []*ReportChange `json:"regressions,omitempty"`
+
+	Manifest *Manifest `json:"manifest,omitempty"`
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func readReport(path string) (*Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// reports written with -gzip are read as they are
+	var in io.Reader = bufio.NewReader(f)
+	if b, _ := in.(*bufio.Reader).Peek(2); len(b) == 2 && b[0] == 0x1f && b[1] == 0x8b {
+		zr, err := gzip.NewReader(in)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		defer zr.Close()
+		in = zr
+	}
+	r := new(Report)
+	err = json.NewDecoder(in).Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	for _, t := range r.Targets {
+		// older reports only have names, joined by commas
+		if t.Files == nil {
+			t.Files = strings.Split(t.Name, ",")
+		}
+	}
+	return r, nil
+}
+
+// ReadCommits reads commits in the JSON schema of Commit, as an alternative to
+// git log.
+func ReadCommits(path string) ([]*Commit, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var commits []*Commit
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&commits); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	for i, commit := range commits {
+		if commit == nil || commit.ID == "" {
+			return nil, fmt.Errorf("%s: commit %d: missing id", path, i)
+		}
+		for _, diff := range commit.Diff {
+			if diff.File == "" {
+				return nil, fmt.Errorf("%s: commit %s: diff without file", path, commit.ID)
+			}
+			if diff.Add < 0 || diff.Delete < 0 {
+				return nil, fmt.Errorf("%s: commit %s: negative line count for %s", path, commit.ID, diff.File)
+			}
+		}
+	}
+	return commits, nil
+}
+
+// ReportChange is how the score of a target changed between two reports.
+type ReportChange struct {
+	Name   string  `json:"name"`
+	Status string  `json:"status"`
+	Old    float64 `json:"old"`
+	New    float64 `json:"new"`
+	Delta  float64 `json:"delta"`
+}
+
+type ByDelta []*ReportChange
+
+func (s ByDelta) Len() int      { return len(s) }
+func (s ByDelta) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s ByDelta) Less(i, j int) bool {
+	di, dj := math.Abs(s[i].Delta), math.Abs(s[j].Delta)
+	if di != dj {
+		return di > dj
+	}
+	return s[i].Name < s[j].Name
+}
+
+// CompareReports returns targets that improved, worsened, appeared or
+// disappeared from old to new, sorted by the magnitude of change.
+func CompareReports(old, new *Report) (changes []*ReportChange) {
+	oldScore := make(map[string]float64)
+	for _, t := range old.Targets {
+		oldScore[t.Name] = t.Score
+	}
+	seen := make(map[string]bool)
+	for _, t := range new.Targets {
+		seen[t.Name] = true
+		c := &ReportChange{Name: t.Name, New: t.Score}
+		if score, ok := oldScore[t.Name]; ok {
+			c.Old = score
+			switch {
+			case t.Score > score:
+				c.Status = "worsened"
+			case t.Score < score:
+				c.Status = "improved"
+			default:
+				continue
+			}
+		} else {
+			c.Status = "appeared"
+		}
+		c.Delta = c.New - c.Old
+		changes = append(changes, c)
+	}
+	for _, t := range old.Targets {
+		if seen[t.Name] {
+			continue
+		}
+		changes = append(changes, &ReportChange{
+			Name:   t.Name,
+			Status: "disappeared",
+			Old:    t.Score,
+			Delta:  -t.Score,
+		})
+	}
+	sort.Sort(ByDelta(changes))
+	return
+}
+
+// findRegressions returns targets of new that worsened by more than
+// -regression-delta since old, or appeared scoring at least -regression-floor.
+func findRegressions(old, new *Report) (regressions []*ReportChange) {
+	for _, c := range CompareReports(old, new) {
+		switch {
+		case c.Status == "worsened" && c.Delta > *regressionDelta,
+			c.Status == "appeared" && c.New >= *regressionFloor:
+			regressions = append(regressions, c)
+		}
+	}
+	return
+}
+
+func printChanges(w io.Writer, changes []*ReportChange) {
+	for _, c := range changes {
+		fmt.Fprintf(w, "%+8.*f %-11s %s %8.*f -> %.*f\n",
+			*precision, c.Delta, c.Status, pad(shorten(c.Name, 40), 40), *precision, c.Old, *precision, c.New)
+	}
+}
+
+func printRegressions(regressions []*ReportChange) {
+	if len(regressions) == 0 {
+		return
+	}
+	fmt.Println("regressions:")
+	printChanges(os.Stdout, regressions)
+	fmt.Println()
+}
+
+// DiffReports compares two JSON reports and writes the changes in -format.
+func DiffReports(w io.Writer, oldPath, newPath string) error {
+	old, err := readReport(oldPath)
+	if err != nil {
+		return err
+	}
+	new, err := readReport(newPath)
+	if err != nil {
+		return err
+	}
+	changes := CompareReports(old, new)
+	if *format == "json" {
+		if changes == nil {
+			changes = []*ReportChange{}
+		}
+		return writeJSON(w, changes)
+	}
+	printChanges(w, changes)
+	return nil
+}
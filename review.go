@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// findReview sets Review to the fewest commits of t that together make share
+// of its edit score, largest contribution first.
+func (t *Target) findReview(share float64) {
+	t.Review = nil
+	var total float64
+	order := make([]int, len(t.Commit))
+	for i, c := range t.contrib {
+		order[i] = i
+		total += c
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return t.contrib[order[i]] > t.contrib[order[j]]
+	})
+	var sum float64
+	for _, i := range order {
+		if sum >= share*total {
+			break
+		}
+		sum += t.contrib[i]
+		t.Review = append(t.Review, t.Commit[i].ID)
+	}
+}
+
+func printReview(t *Target) {
+	if len(t.Review) == 0 {
+		return
+	}
+	commits := make(map[string]*Commit)
+	for _, commit := range t.Commit {
+		commits[commit.ID] = commit
+	}
+	fmt.Printf("         %.0f%% of churn from %d of %d commits:\n", *reviewShare*100, len(t.Review), len(t.Commit))
+	for _, id := range t.Review {
+		commit := commits[id]
+		fmt.Printf("           %s %s (%s)\n", paint(colorDim, shortID(id)), commit.Subject, commit.Author.Name)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"time"
+)
+
+// rewriteShare is how much of a file a commit must delete to rewrite it.
+const rewriteShare = 0.9
+
+var lineCountAtCache = make(map[string]int)
+
+// GitLineCountAt returns the number of lines of file after commit, or 0 if
+// it does not exist. Results are cached by commit and file.
+func GitLineCountAt(ctx context.Context, commitID, file string) int {
+	key := commitID + ":" + file
+	if n, ok := lineCountAtCache[key]; ok {
+		return n
+	}
+	var n int
+	if b, err := exec.CommandContext(ctx, "git", "show", key).Output(); err == nil {
+		n = bytes.Count(b, []byte("\n"))
+	}
+	lineCountAtCache[key] = n
+	return n
+}
+
+// isRewrite reports whether commit deleted nearly all lines that file had
+// before it.
+func isRewrite(ctx context.Context, commit *Commit, diff Diff) bool {
+	if diff.Delete == 0 {
+		return false
+	}
+	before := GitLineCountAt(ctx, commit.ID, diff.File) - diff.Add + diff.Delete
+	return before > 0 && float64(diff.Delete) >= rewriteShare*float64(before)
+}
+
+// findRewrites returns when each file that can be a target was last
+// rewritten.
+func findRewrites(ctx context.Context, commits []*Commit) map[string]time.Time {
+	rewrites := make(map[string]time.Time)
+	exts := fileExts()
+	for _, commit := range commits {
+		for _, diff := range commit.Diff {
+			if !keepFile(diff.File) || !hasExt(diff.File, exts) || !inScope(diff.File) {
+				continue
+			}
+			if t, ok := rewrites[diff.File]; ok && !commit.Author.Time.After(t) {
+				continue
+			}
+			if isRewrite(ctx, commit, diff) {
+				rewrites[diff.File] = commit.Author.Time
+			}
+		}
+	}
+	return rewrites
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+const sarifRuleID = "refactor-hotspot"
+
+// sarifLog is the subset of SARIF 2.1.0 written by -format=sarif.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string                 `json:"ruleId"`
+	Level      string                 `json:"level"`
+	Message    sarifMessage           `json:"message"`
+	Locations  []sarifLocation        `json:"locations"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine"`
+}
+
+// sarifLevel maps score to a SARIF level by -sarif-error and -sarif-warning.
+func sarifLevel(score float64) string {
+	switch {
+	case score >= *sarifError:
+		return "error"
+	case score >= *sarifWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// writeSARIF writes each target of r as a result, located at all of its
+// files, and at the hot range in its hot file.
+func writeSARIF(w io.Writer, r *Report) error {
+	results := []sarifResult{}
+	for _, t := range r.Targets {
+		msg := fmt.Sprintf("changed by %d commits, scoring %.*f", len(t.Commit), *precision, t.Score)
+		if len(t.Files) > 1 {
+			msg = fmt.Sprintf("%d files that change together, %s", len(t.Files), msg)
+		}
+		if len(t.Reason) > 0 {
+			msg += fmt.Sprintf("; most changed back and forth: %s", t.Reason[0].Line)
+		}
+		res := sarifResult{
+			RuleID:  sarifRuleID,
+			Level:   sarifLevel(t.Score),
+			Message: sarifMessage{msg},
+			Properties: map[string]interface{}{
+				"score":   t.Score,
+				"commits": len(t.Commit),
+				"target":  t.Name,
+			},
+		}
+		for _, file := range t.Files {
+			loc := sarifLocation{sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{file}}}
+			if file == t.HotFile && t.HotStart > 0 {
+				loc.PhysicalLocation.Region = &sarifRegion{t.HotStart, t.HotEnd}
+			}
+			res.Locations = append(res.Locations, loc)
+		}
+		results = append(results, res)
+	}
+	return writeJSON(w, &sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{sarifDriver{
+				Name: "refactor",
+				Rules: []sarifRule{{
+					ID:               sarifRuleID,
+					ShortDescription: sarifMessage{"Code that is changed back and forth often"},
+				}},
+			}},
+			Results: results,
+		}},
+	})
+}
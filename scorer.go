@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ExternalScore runs the scorer command through the shell. The target is
+// written to its stdin as JSON, with the built-in score in "score", and the
+// command prints the new score as a single number on stdout.
+func ExternalScore(ctx context.Context, command string, t *Target) (score float64, err error) {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%v: %s", err, msg)
+		}
+		return
+	}
+	score, err = strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	return
+}
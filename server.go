@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+// server runs one analysis at a time since flags and caches are shared.
+type server struct {
+	mu    sync.Mutex
+	cache map[string]*Report
+	refs  string
+}
+
+// Serve serves JSON reports on addr until ctx is done.
+//
+//	GET /analyze?after=...&before=...&ext=...&target=...
+//	GET /healthz
+func Serve(ctx context.Context, addr string) error {
+	s := &server{cache: make(map[string]*Report)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/analyze", s.analyze)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	err := srv.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return ctx.Err()
+	}
+	return err
+}
+
+func (s *server) analyze(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ctx := r.Context()
+	refs, err := gitRefs(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if refs != s.refs {
+		s.refs = refs
+		s.cache = make(map[string]*Report)
+		resetHeadCache()
+	}
+
+	q := r.URL.Query()
+	key := q.Encode()
+	report, ok := s.cache[key]
+	if !ok {
+		restore, err := setQuery(q)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		a, err := Analyze(ctx)
+		if err == nil {
+			report = a.Report()
+			// flags of the request are in effect until restore
+			report.Manifest = newManifest(ctx)
+		}
+		restore()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.cache[key] = report
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, report)
+}
+
+// setQuery overrides flags with query parameters of a request and returns a
+// function that restores them.
+func setQuery(q url.Values) (restore func(), err error) {
+	oldAfter, oldBefore, oldExt, oldTarget := *after, *before, *ext, *topTarget
+	restore = func() {
+		*after, *before, *ext, *topTarget = oldAfter, oldBefore, oldExt, oldTarget
+	}
+	if v := q.Get("after"); v != "" {
+		*after = v
+	}
+	if v := q.Get("before"); v != "" {
+		*before = v
+	}
+	if v := q.Get("ext"); v != "" {
+		if extWeights != nil {
+			// the extensions are those of the weights
+			restore()
+			return nil, errors.New("ext cannot be used with -ext-weights")
+		}
+		*ext = v
+	}
+	if v := q.Get("target"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			restore()
+			return nil, err
+		}
+		*topTarget = n
+	}
+	return restore, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS targets (
+	run_id INTEGER NOT NULL,
+	timestamp TEXT NOT NULL,
+	file TEXT NOT NULL,
+	score REAL NOT NULL,
+	commits INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS targets_file ON targets (file, run_id);
+`
+
+// WriteSQLite appends targets of this run to the database at path. Like git,
+// sqlite3 is run as an external command so that no driver is needed.
+func WriteSQLite(ctx context.Context, path string, targets []*Target, now time.Time) error {
+	var sql bytes.Buffer
+	sql.WriteString(sqliteSchema)
+	sql.WriteString("BEGIN;\n")
+	sql.WriteString("CREATE TEMP TABLE run AS SELECT COALESCE(MAX(run_id), 0) + 1 AS id FROM targets;\n")
+	ts := sqlQuote(now.UTC().Format(time.RFC3339))
+	for _, t := range targets {
+		fmt.Fprintf(&sql, "INSERT INTO targets SELECT id, %s, %s, %g, %d FROM run;\n",
+			ts, sqlQuote(t.Name), t.Score, len(t.Commit))
+	}
+	sql.WriteString("COMMIT;\n")
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sqlite3", "-bail", path)
+	cmd.Stdin = &sql
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("sqlite3: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return nil
+}
+
+func sqlQuote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
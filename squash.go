@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// squashCommits coalesces runs of non-merge commits by the same author, each
+// within window of the previous one, into one commit with their summed diffs.
+// The result is newest first; squashed commits keep the ID of their newest
+// member and list the older members in Squashed, oldest first.
+func squashCommits(commits []*Commit, window time.Duration) []*Commit {
+	// commits are newest first; reversed, commits of the same second stay
+	// in order
+	sorted := make([]*Commit, len(commits))
+	for i, commit := range commits {
+		sorted[len(commits)-1-i] = commit
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Author.Time.Before(sorted[j].Author.Time)
+	})
+	var groups [][]*Commit
+	for _, commit := range sorted {
+		if n := len(groups); n > 0 {
+			last := groups[n-1][len(groups[n-1])-1]
+			if len(commit.Parents) < 2 && len(last.Parents) < 2 &&
+				commit.Author.Name == last.Author.Name &&
+				commit.Author.Time.Sub(last.Author.Time) <= window {
+				groups[n-1] = append(groups[n-1], commit)
+				continue
+			}
+		}
+		groups = append(groups, []*Commit{commit})
+	}
+	squashed := make([]*Commit, len(groups))
+	for i, group := range groups {
+		commit := group[len(group)-1]
+		if len(group) > 1 {
+			c := *commit
+			c.Diff = sumDiffs(group)
+			c.Squashed = group[:len(group)-1]
+			commit = &c
+		}
+		squashed[len(groups)-1-i] = commit
+	}
+	return squashed
+}
+
+// sumDiffs adds up the diffs of commits per file, in order of appearance.
+func sumDiffs(commits []*Commit) (diffs []Diff) {
+	index := make(map[string]int)
+	for _, commit := range commits {
+		for _, diff := range commit.Diff {
+			i, ok := index[diff.File]
+			if !ok {
+				index[diff.File] = len(diffs)
+				diffs = append(diffs, diff)
+				continue
+			}
+			diffs[i].Add += diff.Add
+			diffs[i].Delete += diff.Delete
+			if diffs[i].From == "" {
+				diffs[i].From = diff.From
+			}
+		}
+	}
+	return
+}
+
+// squashDiff returns a DiffFunc that diffs each squashed commit as one patch
+// of all of its members, so that lines changed back and forth within it are
+// not reasons.
+func squashDiff(commits []*Commit, diff DiffFunc) DiffFunc {
+	members := make(map[string][]*Commit)
+	for _, commit := range commits {
+		if len(commit.Squashed) > 0 {
+			members[commit.ID] = append(append([]*Commit(nil), commit.Squashed...), commit)
+		}
+	}
+	cache := make(map[string]*Patch)
+	return func(ctx context.Context, commitID string) (*Patch, error) {
+		group, ok := members[commitID]
+		if !ok {
+			return diff(ctx, commitID)
+		}
+		if p, ok := cache[commitID]; ok {
+			return p, nil
+		}
+		p := &Patch{API: make(map[string]int), Code: make(map[string]int)}
+		for _, commit := range group {
+			q, err := diff(ctx, commit.ID)
+			if err != nil {
+				return nil, err
+			}
+			p.Add = append(p.Add, q.Add...)
+			p.Del = append(p.Del, q.Del...)
+			p.Hunks = append(p.Hunks, q.Hunks...)
+			for file, n := range q.API {
+				p.API[file] += n
+			}
+			for file, n := range q.Code {
+				p.Code[file] += n
+			}
+		}
+		cache[commitID] = p
+		return p, nil
+	}
+}
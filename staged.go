@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// stagedID is the ID of the synthetic commit of staged changes.
+const stagedID = "staged"
+
+// GitStaged returns the changes in the index as a synthetic commit, or nil if
+// nothing is staged. Its diff is cached for GitDiff.
+func GitStaged(ctx context.Context) (*Commit, error) {
+	b, err := exec.CommandContext(ctx, "git", "-c", "core.quotepath=false", "diff", "--cached", "--numstat").Output()
+	if err != nil {
+		return nil, err
+	}
+	commit := &Commit{
+		ID:      stagedID,
+		Author:  Author{Name: "staged", Time: time.Now()},
+		Message: []string{"staged changes"},
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		// renames resolve as in git log
+		commit.addNumstat(line)
+	}
+	if len(commit.Diff) == 0 {
+		return nil, nil
+	}
+	b, err = exec.CommandContext(ctx, "git", "-c", "core.quotepath=false", "diff", "--cached").Output()
+	if err != nil {
+		return nil, err
+	}
+	diffCache[stagedID] = ParseDiff(b, nil)
+	return commit, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Stats summarizes the distribution of target scores.
+type Stats struct {
+	Median float64 `json:"median"`
+	P90    float64 `json:"p90"`
+	P99    float64 `json:"p99"`
+
+	// Top80 is the number of highest-scored targets that account for 80% of
+	// the total score.
+	Top80 int `json:"top80"`
+}
+
+// percentile uses the nearest-rank method on ascending scores.
+func percentile(scores []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(scores))))
+	if rank < 1 {
+		rank = 1
+	}
+	return scores[rank-1]
+}
+
+func computeStats(targets []*Target) *Stats {
+	if len(targets) == 0 {
+		return nil
+	}
+	scores := make([]float64, len(targets))
+	var total float64
+	for i, t := range targets {
+		scores[i] = t.Score
+		total += t.Score
+	}
+	sort.Float64s(scores)
+	stats := &Stats{
+		Median: percentile(scores, 50),
+		P90:    percentile(scores, 90),
+		P99:    percentile(scores, 99),
+	}
+	var sum float64
+	for i := len(scores) - 1; i >= 0; i-- {
+		sum += scores[i]
+		stats.Top80++
+		if sum >= 0.8*total {
+			break
+		}
+	}
+	return stats
+}
+
+func printStats(stats *Stats) {
+	if stats == nil {
+		return
+	}
+	fmt.Printf("score median: %.*f, p90: %.*f, p99: %.*f, 80%% of score in %d targets\n",
+		*precision, stats.Median, *precision, stats.P90, *precision, stats.P99, stats.Top80)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// conventionalRegexp matches conventional commit subjects, like
+	// "fix(parser)!: handle empty input". Only known types match, so that
+	// "area: summary" subjects of git or Linux are kept as they are.
+	conventionalRegexp = regexp.MustCompile(`^(?i)(feat|fix|chore|docs|refactor|test|perf|build|ci|style|revert)(?:\(([^)]*)\))?!?: +(.+)$`)
+	// ticketRegexp matches ticket IDs prefixed to subjects, like "[ABC-123]"
+	// or "ABC-123:".
+	ticketRegexp = regexp.MustCompile(`^(?:\[[A-Z][A-Z0-9]*-[0-9]+\]|[A-Z][A-Z0-9]*-[0-9]+:) *`)
+)
+
+// parseSubject splits the subject of commit into conventional commit type,
+// scope and description. Subject is the description, or the subject without
+// ticket IDs if it is not a conventional commit.
+func (c *Commit) parseSubject() {
+	c.Type, c.Scope, c.Subject = "", "", ""
+	if len(c.Message) == 0 {
+		return
+	}
+	subject := strings.TrimSpace(ticketRegexp.ReplaceAllString(c.Message[0], ""))
+	if match := conventionalRegexp.FindStringSubmatch(subject); match != nil {
+		c.Type = strings.ToLower(match[1])
+		c.Scope = match[2]
+		subject = match[3]
+	}
+	c.Subject = subject
+}
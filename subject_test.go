@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestParseSubject(t *testing.T) {
+	for _, tt := range []struct {
+		subject, typ, scope, want string
+	}{
+		{"fix(parser)!: handle empty input", "fix", "parser", "handle empty input"},
+		{"Feat: add x", "feat", "", "add x"},
+		{"[ABC-123] docs: explain y", "docs", "", "explain y"},
+		{"remote: fix x", "", "", "remote: fix x"},
+		{"net: drop y", "", "", "net: drop y"},
+		{"ABC-123: plain", "", "", "plain"},
+	} {
+		c := &Commit{Message: []string{tt.subject}}
+		c.parseSubject()
+		if c.Type != tt.typ || c.Scope != tt.scope || c.Subject != tt.want {
+			t.Errorf("%q: type %q, scope %q, subject %q; want %q, %q, %q",
+				tt.subject, c.Type, c.Scope, c.Subject, tt.typ, tt.scope, tt.want)
+		}
+	}
+}
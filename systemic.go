@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SystemicReason is a reason line that recurs in many files: a pattern worth
+// a codemod rather than a per-file refactor.
+type SystemicReason struct {
+	Line  string `json:"line"`
+	Files int    `json:"files"`
+}
+
+type BySystemicFiles []*SystemicReason
+
+func (s BySystemicFiles) Len() int      { return len(s) }
+func (s BySystemicFiles) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s BySystemicFiles) Less(i, j int) bool {
+	if s[i].Files != s[j].Files {
+		return s[i].Files > s[j].Files
+	}
+	return s[i].Line < s[j].Line
+}
+
+// findSystemicReasons returns the top k reason lines of all targets that
+// were changed in at least min files.
+func findSystemicReasons(targets []*Target, min, k int) (reasons []*SystemicReason) {
+	display := make(map[string]string)
+	files := make(map[string]map[string]bool)
+	for _, t := range targets {
+		for _, reason := range t.Reason {
+			key := reasonKey(reason.Line)
+			if _, ok := files[key]; !ok {
+				display[key] = reason.Line
+				files[key] = make(map[string]bool)
+			}
+			for file := range reason.files {
+				files[key][file] = true
+			}
+		}
+	}
+	for key, f := range files {
+		if len(f) >= min {
+			reasons = append(reasons, &SystemicReason{Line: display[key], Files: len(f)})
+		}
+	}
+	sort.Sort(BySystemicFiles(reasons))
+	if len(reasons) > k {
+		reasons = reasons[:k]
+	}
+	return
+}
+
+func printSystemicReasons(reasons []*SystemicReason) {
+	if len(reasons) == 0 {
+		return
+	}
+	fmt.Println("systemic reasons:")
+	for _, r := range reasons {
+		fmt.Printf("    %4d files %s\n", r.Files, paint(colorMuted, r.Line))
+	}
+	fmt.Println()
+}
+
+// GlobalReason is a reason line with its count summed across files.
+type GlobalReason struct {
+	Line  string   `json:"line"`
+	Count int      `json:"count"`
+	Files []string `json:"files"`
+}
+
+type ByGlobalCount []*GlobalReason
+
+func (s ByGlobalCount) Len() int      { return len(s) }
+func (s ByGlobalCount) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s ByGlobalCount) Less(i, j int) bool {
+	if s[i].Count != s[j].Count {
+		return s[i].Count > s[j].Count
+	}
+	return s[i].Line < s[j].Line
+}
+
+// findGlobalReasons returns the top k reason lines of the whole repository.
+// A reason is counted for a single-file target only if the line changed in
+// that file, since groups and the other files of the same commits repeat it.
+func findGlobalReasons(targets []*Target, k int) (reasons []*GlobalReason) {
+	m := make(map[string]*GlobalReason)
+	for _, t := range targets {
+		if len(t.Files) != 1 {
+			continue
+		}
+		for _, reason := range t.Reason {
+			if !reason.files[t.Files[0]] {
+				continue
+			}
+			key := reasonKey(reason.Line)
+			r, ok := m[key]
+			if !ok {
+				r = &GlobalReason{Line: reason.Line}
+				m[key] = r
+			}
+			r.Count += reason.Count
+			r.Files = append(r.Files, t.Name)
+		}
+	}
+	for _, r := range m {
+		sort.Strings(r.Files)
+		reasons = append(reasons, r)
+	}
+	sort.Sort(ByGlobalCount(reasons))
+	if len(reasons) > k {
+		reasons = reasons[:k]
+	}
+	return
+}
+
+func printGlobalReasons(reasons []*GlobalReason) {
+	if len(reasons) == 0 {
+		return
+	}
+	fmt.Println("global reasons:")
+	for _, r := range reasons {
+		fmt.Printf("    %4d %s\n", r.Count, paint(colorMuted, r.Line))
+		fmt.Printf("         in %s\n", shorten(strings.Join(r.Files, ", "), 70))
+	}
+	fmt.Println()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplateData is what -template-file is executed with.
+type TemplateData struct {
+	*Report
+	After  string
+	Before string
+	Time   time.Time
+}
+
+var templateFuncs = template.FuncMap{
+	"shorten":    shorten,
+	"shortID":    shortID,
+	"formatTime": formatTime,
+	"join":       strings.Join,
+	"paint":      paint,
+}
+
+var outputTemplate *template.Template
+
+func parseTemplate(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	outputTemplate, err = template.New(path).Funcs(templateFuncs).Parse(string(b))
+	return err
+}
+
+func writeTemplate(w io.Writer, r *Report) error {
+	return outputTemplate.Execute(w, &TemplateData{
+		Report: r,
+		After:  *after,
+		Before: *before,
+		Time:   time.Now(),
+	})
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"path"
+	"strings"
+)
+
+// testTemplates are -test-files: paths of the tests of a file, made of
+// {dir}, {base} and {ext} of the file.
+var testTemplates []string
+
+// testFilesOf returns the possible test files of file.
+func testFilesOf(file string) (tests []string) {
+	ext := path.Ext(file)
+	r := strings.NewReplacer(
+		"{dir}", path.Dir(file),
+		"{base}", strings.TrimSuffix(path.Base(file), ext),
+		"{ext}", ext,
+	)
+	for _, tmpl := range testTemplates {
+		if test := path.Clean(r.Replace(tmpl)); test != file {
+			tests = append(tests, test)
+		}
+	}
+	return
+}
+
+// isTestFile reports whether file is named like the test of another file.
+func isTestFile(file string) bool {
+	ext := path.Ext(file)
+	for _, tmpl := range testTemplates {
+		pattern := strings.NewReplacer("{dir}", "*", "{base}", "*", "{ext}", ext).Replace(path.Base(tmpl))
+		if ok, _ := path.Match(pattern, path.Base(file)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// findStaleTests counts, for each single-file target that has a test file,
+// the commits that changed the test too, and flags the target when fewer
+// than -test-rate of its commits did.
+func findStaleTests(ctx context.Context, targets []*Target) error {
+	head, err := GitLsTree(ctx, "HEAD")
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		t.TestCommits, t.TestsStale = 0, false
+		if len(t.Files) != 1 || isTestFile(t.Files[0]) {
+			continue
+		}
+		tests := make(map[string]bool)
+		for _, test := range testFilesOf(t.Files[0]) {
+			tests[test] = true
+		}
+		exists := false
+		for test := range tests {
+			exists = exists || head[test]
+		}
+		for _, commit := range t.Commit {
+			for _, diff := range commit.Diff {
+				if tests[diff.File] {
+					t.TestCommits++
+					exists = true
+					break
+				}
+			}
+		}
+		if !exists {
+			continue
+		}
+		t.TestsStale = float64(t.TestCommits) < *testRate*float64(len(t.Commit))
+	}
+	return nil
+}
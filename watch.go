@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// gitRefs returns HEAD and all refs, which change whenever commits land.
+func gitRefs(ctx context.Context) (string, error) {
+	b, err := exec.CommandContext(ctx, "git", "show-ref", "--head").Output()
+	if e, ok := err.(*exec.ExitError); ok && e.ExitCode() == 1 {
+		// no refs yet
+		return "", nil
+	}
+	return string(b), err
+}
+
+// Watch runs the analysis and runs it again whenever refs change.
+func Watch(ctx context.Context, interval time.Duration) error {
+	var last string
+	for {
+		refs, err := gitRefs(ctx)
+		if err != nil {
+			return err
+		}
+		if refs != last {
+			last = refs
+			resetHeadCache()
+
+			fmt.Print("\x1b[H\x1b[2J")
+			fmt.Printf("%s\n\n", formatTime(time.Now()))
+			if err := run(ctx); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// wideRanges are East Asian wide and fullwidth runes, which take two columns
+// in a terminal.
+var wideRanges = [][2]rune{
+	{0x1100, 0x115f},
+	{0x2e80, 0x303e},
+	{0x3041, 0x33ff},
+	{0x3400, 0x4dbf},
+	{0x4e00, 0x9fff},
+	{0xa000, 0xa4cf},
+	{0xac00, 0xd7a3},
+	{0xf900, 0xfaff},
+	{0xfe30, 0xfe4f},
+	{0xff00, 0xff60},
+	{0xffe0, 0xffe6},
+	{0x1f300, 0x1f64f},
+	{0x1f900, 0x1f9ff},
+	{0x20000, 0x2fffd},
+	{0x30000, 0x3fffd},
+}
+
+// runeWidth returns the number of terminal columns of r.
+func runeWidth(r rune) int {
+	if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	for _, w := range wideRanges {
+		if r >= w[0] && r <= w[1] {
+			return 2
+		}
+	}
+	return 1
+}
+
+// displayWidth returns the number of terminal columns of s.
+func displayWidth(s string) (n int) {
+	for _, r := range s {
+		n += runeWidth(r)
+	}
+	return
+}
+
+// pad fills s with spaces up to l columns, like %-*s but by display width.
+func pad(s string, l int) string {
+	if w := displayWidth(s); w < l {
+		return s + strings.Repeat(" ", l-w)
+	}
+	return s
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var windowsRegexp = regexp.MustCompile(`^([1-9][0-9]*)([dw])$`)
+
+// parseWindows parses -windows, like 12w, into a count and a window size.
+func parseWindows(s string) (n int, size time.Duration, err error) {
+	match := windowsRegexp.FindStringSubmatch(s)
+	if match == nil {
+		return 0, 0, fmt.Errorf("invalid -windows %q: must be a count of days or weeks, like 30d or 12w", s)
+	}
+	n, _ = strconv.Atoi(match[1])
+	size = 24 * time.Hour
+	if match[2] == "w" {
+		size *= 7
+	}
+	return
+}
+
+// WindowReport is the report of one of -windows.
+type WindowReport struct {
+	After  time.Time `json:"after"`
+	Before time.Time `json:"before"`
+	*Report
+}
+
+// WriteWindows loads the commits of all -windows before -before at once, and
+// writes a JSON object of reports keyed by the start of each window.
+func WriteWindows(ctx context.Context, w io.Writer) error {
+	n, size, err := parseWindows(*windows)
+	if err != nil {
+		return err
+	}
+	end, err := time.Parse(time.RFC3339, *before)
+	if err != nil {
+		return fmt.Errorf("-windows needs -before in RFC 3339, like %s", time.Now().Format(time.RFC3339))
+	}
+	start := end.Add(-time.Duration(n) * size)
+	*after = start.Format(time.RFC3339)
+	commits, diff, err := loadCommits(ctx)
+	if err != nil {
+		return err
+	}
+	buckets := make([][]*Commit, n)
+	for _, commit := range commits {
+		at := commit.Author.Time
+		if at.Before(start) || !at.Before(end) {
+			continue
+		}
+		i := int(at.Sub(start) / size)
+		buckets[i] = append(buckets[i], commit)
+	}
+	reports := make(map[string]*WindowReport)
+	m := newManifest(ctx)
+	for i, bucket := range buckets {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		a, err := analyzeCommits(ctx, bucket, diff)
+		if err != nil {
+			return err
+		}
+		r := &WindowReport{
+			After:  start.Add(time.Duration(i) * size).In(location),
+			Before: start.Add(time.Duration(i+1) * size).In(location),
+			Report: a.Report(),
+		}
+		if r.Targets == nil {
+			r.Targets = []*Target{}
+		}
+		r.Manifest = m
+		reports[r.After.Format(time.RFC3339)] = r
+	}
+	return writeJSON(w, reports)
+}